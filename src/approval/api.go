@@ -0,0 +1,395 @@
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"norsetinge/src/builder"
+	"norsetinge/src/deployer"
+)
+
+// apiArticle is the JSON representation of an article in the publish flow
+type apiArticle struct {
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Author      string        `json:"author"`
+	State       ApprovalState `json:"state"`
+	StateSince  time.Time     `json:"state_since"`
+	PreviewURL  string        `json:"preview_url,omitempty"`
+	ApprovalURL string        `json:"approval_url,omitempty"` // Only for a verified editor
+	Comments    string        `json:"comments,omitempty"`
+	PublishAt   *time.Time    `json:"publish_at,omitempty"`
+	Required    int           `json:"required_approvals"`
+	Approvals   []Approval    `json:"approvals"`
+	History     []StateChange `json:"history,omitempty"`
+}
+
+// apiRun is the JSON representation of a build or deploy result
+type apiRun struct {
+	Finished   time.Time   `json:"finished"`
+	DurationMS int64       `json:"duration_ms"`
+	OK         bool        `json:"ok"`
+	Error      string      `json:"error,omitempty"`
+	Changes    *apiChanges `json:"changes,omitempty"`  // Builds only
+	Rollback   string      `json:"rollback,omitempty"` // Restored deploy commit, rollbacks only
+}
+
+// apiChanges lists the article IDs whose content a build added, changed or removed
+type apiChanges struct {
+	Added     []string `json:"added"`
+	Changed   []string `json:"changed"`
+	Removed   []string `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// apiStatus is the response of GET /api/v1/status
+type apiStatus struct {
+	Pending        int                     `json:"pending"`
+	LastBuild      *apiRun                 `json:"last_build"`
+	LastDeploy     *apiRun                 `json:"last_deploy"`
+	PendingDeletes *deployer.DeployReport  `json:"pending_deletes,omitempty"` // Deploy awaiting confirm_deletes
+	Deploy         builder.SchedulerStatus `json:"deploy"`                    // Running and queued build+deploy
+}
+
+// rollbackListLimit is the number of deploys offered for rollback
+const rollbackListLimit = 20
+
+// apiRoutes registers the versioned JSON API
+func (s *Server) apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/articles", s.apiListArticles)
+	mux.HandleFunc("GET /api/v1/articles/{id}", s.apiGetArticle)
+	mux.HandleFunc("POST /api/v1/articles/{id}/approve", requireJSON(s.apiApprove))
+	mux.HandleFunc("POST /api/v1/articles/{id}/approve-deploy", requireJSON(s.apiApproveAndDeploy))
+	mux.HandleFunc("POST /api/v1/articles/{id}/reject", requireJSON(s.apiReject))
+	mux.HandleFunc("POST /api/v1/articles/{id}/revise", requireJSON(s.apiRevise))
+	mux.HandleFunc("POST /api/v1/build", requireJSON(s.apiBuild))
+	mux.HandleFunc("POST /api/v1/deploy", requireJSON(s.apiDeploy))
+	mux.HandleFunc("POST /api/v1/deploy/dry-run", requireJSON(s.apiDryRun))
+	mux.HandleFunc("GET /api/v1/deploys", s.apiDeploys)
+	mux.HandleFunc("POST /api/v1/rollback", requireJSON(s.apiRollback))
+	mux.HandleFunc("GET /api/v1/previews", s.apiPreviews)
+	mux.HandleFunc("GET /api/v1/status", s.apiStatus)
+}
+
+// requireJSON rejects POSTs without Content-Type application/json. Browsers cannot send
+// that cross-site without a CORS preflight, so a form or link on another page cannot act.
+func requireJSON(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// apiListArticles lists articles pending approval, or in the states given by ?state=A,B
+func (s *Server) apiListArticles(w http.ResponseWriter, r *http.Request) {
+	states := []ApprovalState{StatePendingApproval}
+	if param := r.URL.Query().Get("state"); param != "" {
+		states = nil
+		for _, state := range strings.Split(param, ",") {
+			states = append(states, ApprovalState(strings.TrimSpace(state)))
+		}
+	}
+
+	editor := s.verifiedEditor(r)
+	articles := []apiArticle{}
+	for _, entry := range s.flow.InState(states...) {
+		articles = append(articles, s.toAPIArticle(entry, false, editor))
+	}
+
+	writeJSON(w, http.StatusOK, articles)
+}
+
+// apiGetArticle returns one article with its state history
+func (s *Server) apiGetArticle(w http.ResponseWriter, r *http.Request) {
+	entry, exists := s.flow.Get(normalizeID(r.PathValue("id")))
+	if !exists {
+		writeAPIError(w, errNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.toAPIArticle(entry, true, s.verifiedEditor(r)))
+}
+
+// apiApprove approves an article (deployed by the next periodic build)
+func (s *Server) apiApprove(w http.ResponseWriter, r *http.Request) {
+	s.apiAction(w, r, func(id, editor string) error {
+		_, err := s.approve(id, editor, false)
+		return err
+	})
+}
+
+// apiApproveAndDeploy approves an article and deploys the site immediately
+func (s *Server) apiApproveAndDeploy(w http.ResponseWriter, r *http.Request) {
+	s.apiAction(w, r, func(id, editor string) error {
+		_, err := s.approve(id, editor, true)
+		return err
+	})
+}
+
+// apiReject rejects an article
+func (s *Server) apiReject(w http.ResponseWriter, r *http.Request) {
+	s.apiAction(w, r, s.reject)
+}
+
+// apiRevise sends an article back to the author. Body: {"comments": "..."}
+func (s *Server) apiRevise(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Comments string `json:"comments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	comments := strings.TrimSpace(req.Comments)
+	if comments == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "comments are required"})
+		return
+	}
+
+	s.apiAction(w, r, func(id, editor string) error { return s.revise(id, editor, comments) })
+}
+
+// apiAction runs a workflow action as the request's editor and responds with the article's
+// new state. An approval that still needs more approvers leaves it in PendingApproval.
+func (s *Server) apiAction(w http.ResponseWriter, r *http.Request, action func(id, editor string) error) {
+	id := normalizeID(r.PathValue("id"))
+
+	editor, ok := s.apiEditor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := action(id, editor); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	entry, _ := s.flow.Get(id)
+	writeJSON(w, http.StatusOK, s.toAPIArticle(entry, false, editor))
+}
+
+// apiBuild builds the full site without deploying
+func (s *Server) apiBuild(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.apiEditor(w, r); !ok {
+		return
+	}
+	if err := s.Build(); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	s.apiStatus(w, r)
+}
+
+// apiDeploy builds and deploys the full site. A deploy stopped by deploy.max_deletes
+// answers 409 with the dry-run; repeat with {"confirm_deletes": N} to allow N deletes.
+func (s *Server) apiDeploy(w http.ResponseWriter, r *http.Request) {
+	editor, ok := s.apiEditor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ConfirmDeletes int `json:"confirm_deletes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	var err error
+	if req.ConfirmDeletes > 0 {
+		err = s.ConfirmDeploy(req.ConfirmDeletes, editor)
+	} else {
+		err = s.BuildAndDeploy()
+	}
+
+	var thresholdErr *deployer.DeleteThresholdError
+	if errors.As(err, &thresholdErr) {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": thresholdErr.Error(), "report": thresholdErr.Report})
+		return
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	s.apiStatus(w, r)
+}
+
+// apiDryRun reports what deploying the last build would add, change and delete
+func (s *Server) apiDryRun(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.apiEditor(w, r); !ok {
+		return
+	}
+	report, err := s.DryRun()
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// apiDeploys lists the recent deploy commits that can be rolled back to
+func (s *Server) apiDeploys(w http.ResponseWriter, r *http.Request) {
+	deploys, err := s.Deploys(rollbackListLimit)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if deploys == nil {
+		deploys = []deployer.DeployCommit{}
+	}
+	writeJSON(w, http.StatusOK, deploys)
+}
+
+// apiRollback redeploys an earlier deploy commit. Body: {"commit": "<hash>"}
+func (s *Server) apiRollback(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Commit string `json:"commit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Commit) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "commit is required"})
+		return
+	}
+
+	editor, ok := s.apiEditor(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Rollback(strings.TrimSpace(req.Commit), editor); err != nil {
+		if errors.Is(err, deployer.ErrUnknownCommit) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeAPIError(w, err)
+		return
+	}
+	s.apiStatus(w, r)
+}
+
+// apiPreviews lists queued, running and recently finished preview builds
+func (s *Server) apiPreviews(w http.ResponseWriter, r *http.Request) {
+	jobs := s.PreviewJobs()
+	if jobs == nil {
+		jobs = []PreviewJob{}
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// apiStatus reports the number of pending articles and the last build and deploy
+func (s *Server) apiStatus(w http.ResponseWriter, r *http.Request) {
+	s.runMu.Lock()
+	status := apiStatus{
+		Pending:        len(s.flow.InState(StatePendingApproval)),
+		LastBuild:      toAPIRun(s.lastBuild),
+		LastDeploy:     toAPIRun(s.lastDeploy),
+		PendingDeletes: s.pendingDeletes,
+		Deploy:         s.scheduler.Status(),
+	}
+	s.runMu.Unlock()
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// toAPIArticle converts a flow entry, optionally with its state history. The approval
+// URL is signed for editor and left out without one.
+func (s *Server) toAPIArticle(entry *PendingArticle, withHistory bool, editor string) apiArticle {
+	article := apiArticle{
+		ID:         entry.ID,
+		State:      entry.State,
+		StateSince: entry.StateSince(),
+		Comments:   entry.Comments,
+		Required:   entry.Required(),
+		Approvals:  entry.Approvals,
+	}
+	if editor != "" {
+		article.ApprovalURL = s.EditorApprovalURL(entry.ID, editor)
+	}
+	if article.Approvals == nil {
+		article.Approvals = []Approval{}
+	}
+
+	if entry.Article != nil {
+		article.Title = entry.Article.Title
+		article.Author = entry.Article.Author
+		if !entry.Article.PublishAt.IsZero() {
+			article.PublishAt = &entry.Article.PublishAt
+		}
+	}
+	if entry.PreviewPath != "" {
+		article.PreviewURL = "/preview/" + entry.PreviewPath
+	}
+	if withHistory {
+		article.History = entry.History
+	}
+
+	return article
+}
+
+// toAPIRun converts a build or deploy result (nil if none has run yet)
+func toAPIRun(run *runResult) *apiRun {
+	if run == nil {
+		return nil
+	}
+	api := &apiRun{
+		Finished:   run.Finished,
+		DurationMS: run.Duration.Milliseconds(),
+		OK:         run.Err == "",
+		Error:      run.Err,
+	}
+	if run.Rollback != nil {
+		api.Rollback = run.Rollback.Hash
+	}
+	if changes := run.Changes; changes != nil {
+		api.Changes = &apiChanges{
+			Added:     nonNil(changes.Added),
+			Changed:   nonNil(changes.Changed),
+			Removed:   nonNil(changes.Removed),
+			Unchanged: changes.Unchanged,
+		}
+	}
+	return api
+}
+
+// nonNil returns an empty list for nil, so JSON has [] rather than null
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+// writeAPIError maps flow errors to a JSON error response
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := actionErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("Error handling API request: %v", err)
+	}
+
+	message := err.Error()
+	if errors.Is(err, errNotFound) {
+		message = "article not found"
+	}
+
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Warning: Failed to write JSON response: %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package approval
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+	"norsetinge/src/deployer"
+)
+
+func newAPITestServer(t *testing.T) (*Server, http.Handler) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Approval: config.ApprovalConfig{
+			TailscaleHostname: "norsetinge.tailnet.ts.net",
+			Editors:           []config.EditorConfig{{Name: "TB", Login: "tb", Password: "hemmelig"}},
+		},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+
+	for _, id := range []string{"#API001", "#API002"} {
+		path := filepath.Join(tmpDir, strings.TrimPrefix(id, "#")+".md")
+		os.WriteFile(path, []byte("---\nid: \""+id+"\"\ntitle: API "+id+"\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+		article, _ := common.ParseArticle(path)
+		server.flow.Begin(article)
+		server.flow.Update(id, func(p *PendingArticle) { p.PreviewPath = "preview-api/index.html" })
+		server.flow.Transition(id, StatePendingApproval, "")
+	}
+
+	return server, server.routes()
+}
+
+// doAPI sends a request as the configured editor
+func doAPI(t *testing.T, handler http.Handler, method, path, body string, out interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth("tb", "hemmelig")
+	if method == "POST" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v\n%s", method, path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestAPIListAndGet(t *testing.T) {
+	_, handler := newAPITestServer(t)
+
+	var list []apiArticle
+	if code := doAPI(t, handler, "GET", "/api/v1/articles", "", &list); code != http.StatusOK {
+		t.Fatalf("List: expected 200, got %d", code)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 pending articles, got %d", len(list))
+	}
+
+	var article apiArticle
+	if code := doAPI(t, handler, "GET", "/api/v1/articles/api001", "", &article); code != http.StatusOK {
+		t.Fatalf("Get: expected 200, got %d", code)
+	}
+	if article.ID != "#API001" || article.State != StatePendingApproval {
+		t.Errorf("Unexpected article: %+v", article)
+	}
+	if article.PreviewURL != "/preview/preview-api/index.html" {
+		t.Errorf("Unexpected preview URL: %s", article.PreviewURL)
+	}
+	if !strings.HasPrefix(article.ApprovalURL, "https://norsetinge.tailnet.ts.net/approve/API001?") || !strings.Contains(article.ApprovalURL, "editor=tb") {
+		t.Errorf("Unexpected approval URL: %s", article.ApprovalURL)
+	}
+	if len(article.History) != 3 {
+		t.Errorf("Expected 3 history entries, got %d", len(article.History))
+	}
+
+	if code := doAPI(t, handler, "GET", "/api/v1/articles/NOPE01", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown article, got %d", code)
+	}
+}
+
+func TestAPIRequiresEditor(t *testing.T) {
+	server, handler := newAPITestServer(t)
+
+	anonymous := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// An article ID alone is not enough to act
+	if rec := anonymous("POST", "/api/v1/articles/API001/approve", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Anonymous approve: expected 401, got %d", rec.Code)
+	}
+	if entry, _ := server.flow.Get("#API001"); entry.State != StatePendingApproval {
+		t.Errorf("Anonymous approve changed the state to %s", entry.State)
+	}
+
+	// Nor can anyone build or deploy the site
+	for _, path := range []string{"/api/v1/build", "/api/v1/deploy", "/api/v1/deploy/dry-run"} {
+		if rec := anonymous("POST", path, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Anonymous POST %s: expected 401, got %d", path, rec.Code)
+		}
+	}
+	server.runMu.Lock()
+	if server.lastBuild != nil || server.lastPlan != nil {
+		t.Error("Anonymous request built or planned the site")
+	}
+	server.runMu.Unlock()
+
+	// Anonymous listings carry no signed approval links
+	rec := anonymous("GET", "/api/v1/articles", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "approval_url") {
+		t.Errorf("Anonymous listing: expected 200 without approval links, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Without approval.editors API actions are refused, whatever the request carries
+	server.cfg.Approval.Editors = nil
+	for _, path := range []string{"/api/v1/articles/API001/reject", "/api/v1/build", "/api/v1/deploy"} {
+		if code := doAPI(t, handler, "POST", path, "", nil); code != http.StatusForbidden {
+			t.Errorf("POST %s without editors configured: expected 403, got %d", path, code)
+		}
+	}
+}
+
+func TestAPIActions(t *testing.T) {
+	server, handler := newAPITestServer(t)
+
+	// Actions are POST only
+	if code := doAPI(t, handler, "GET", "/api/v1/articles/API001/reject", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET action, got %d", code)
+	}
+
+	// A cross-site form post cannot set Content-Type application/json
+	req := httptest.NewRequest("POST", "/api/v1/articles/API001/reject", strings.NewReader("x=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for form post, got %d", rec.Code)
+	}
+
+	var article apiArticle
+	if code := doAPI(t, handler, "POST", "/api/v1/articles/API001/reject", "", &article); code != http.StatusOK {
+		t.Fatalf("Reject: expected 200, got %d", code)
+	}
+	if article.State != StateRejected {
+		t.Errorf("Expected Rejected, got %s", article.State)
+	}
+
+	// Same rules as the HTML handlers - a decided article cannot be decided again
+	if code := doAPI(t, handler, "POST", "/api/v1/articles/API001/approve", "", nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for approving a rejected article, got %d", code)
+	}
+
+	if code := doAPI(t, handler, "POST", "/api/v1/articles/API002/revise", `{"comments": ""}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty comments, got %d", code)
+	}
+	if code := doAPI(t, handler, "POST", "/api/v1/articles/API002/revise", `{"comments": "Kortere titel"}`, &article); code != http.StatusOK {
+		t.Fatalf("Revise: expected 200, got %d", code)
+	}
+	if article.State != StateRevisionRequested || article.Comments != "Kortere titel" {
+		t.Errorf("Unexpected revised article: %+v", article)
+	}
+
+	var list []apiArticle
+	doAPI(t, handler, "GET", "/api/v1/articles?state=Rejected,RevisionRequested", "", &list)
+	if len(list) != 2 {
+		t.Errorf("Expected 2 decided articles, got %d", len(list))
+	}
+
+	if entry, _ := server.flow.Get("#API002"); entry.Comments != "Kortere titel" {
+		t.Errorf("Comments not stored in flow: %q", entry.Comments)
+	}
+}
+
+func TestAPIBuildStatus(t *testing.T) {
+	server, handler := newAPITestServer(t)
+
+	var status apiStatus
+	if code := doAPI(t, handler, "GET", "/api/v1/status", "", &status); code != http.StatusOK {
+		t.Fatalf("Status: expected 200, got %d", code)
+	}
+	if status.Pending != 2 || status.LastBuild != nil {
+		t.Errorf("Unexpected initial status: %+v", status)
+	}
+
+	// A build waits for a running deploy instead of rewriting public/ under it
+	release := make(chan struct{})
+	deploying := make(chan struct{})
+	go server.scheduler.Exclusive("deploy", func() error {
+		close(deploying)
+		<-release
+		return nil
+	})
+	<-deploying
+
+	codes := make(chan int)
+	go func() { codes <- doAPI(t, handler, "POST", "/api/v1/build", "", nil) }()
+	time.Sleep(50 * time.Millisecond)
+	server.runMu.Lock()
+	built := server.lastBuild != nil
+	server.runMu.Unlock()
+	if built {
+		t.Error("Build ran during a deploy")
+	}
+	close(release)
+
+	// Without hugo the build fails; the failure is reported in the status either way
+	code := <-codes
+
+	doAPI(t, handler, "GET", "/api/v1/status", "", &status)
+	if status.LastBuild == nil {
+		t.Fatal("Expected last build to be recorded")
+	}
+	if (code == http.StatusOK) != status.LastBuild.OK {
+		t.Errorf("Build response %d does not match recorded result %+v", code, status.LastBuild)
+	}
+
+	// public/ no longer matches what is live, until the next deploy
+	if status.Deploy.Undeployed == nil {
+		t.Error("Expected the build to be reported as undeployed")
+	}
+}
+
+func TestAPIDeployConfirmDeletes(t *testing.T) {
+	server, handler := newAPITestServer(t)
+
+	if code := doAPI(t, handler, "POST", "/api/v1/deploy", `{"confirm_deletes": "all"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Deploy with invalid confirm_deletes: expected 400, got %d", code)
+	}
+
+	server.pendingDeletes = &deployer.DeployReport{Mirror: deployer.ChangeSet{Deleted: []string{"a.html", "b.html"}}}
+	var status apiStatus
+	doAPI(t, handler, "GET", "/api/v1/status", "", &status)
+	if status.PendingDeletes == nil || status.PendingDeletes.Deletes() != 2 {
+		t.Errorf("Expected the stopped deploy in the status, got %+v", status.PendingDeletes)
+	}
+
+	// The dry-run plans the last build as it is, without building
+	os.MkdirAll(server.cfg.Hugo.PublicDir, 0755)
+	os.WriteFile(filepath.Join(server.cfg.Hugo.PublicDir, "index.html"), []byte("home"), 0644)
+	var report deployer.DeployReport
+	if code := doAPI(t, handler, "POST", "/api/v1/deploy/dry-run", "", &report); code != http.StatusOK {
+		t.Fatalf("Dry-run: expected 200, got %d", code)
+	}
+	if len(report.Mirror.Added) != 1 || server.lastPlan == nil {
+		t.Errorf("Unexpected dry-run report: %+v", report)
+	}
+	if server.lastBuild != nil {
+		t.Error("Dry-run built the site")
+	}
+}
+
+func TestAPIRollback(t *testing.T) {
+	server, handler := newAPITestServer(t)
+	mirror := server.cfg.Hugo.MirrorDir
+	webhost := t.TempDir()
+	server.cfg.Deploy = config.DeployConfig{Method: "local", Local: config.LocalConfig{Path: webhost}}
+
+	// Without git history there is nothing to roll back to
+	if code := doAPI(t, handler, "GET", "/api/v1/deploys", "", nil); code != http.StatusInternalServerError {
+		t.Errorf("Deploys without a git mirror: expected 500, got %d", code)
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "Norsetinge")
+	t.Setenv("GIT_AUTHOR_EMAIL", "deploy@norsetinge.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Norsetinge")
+	t.Setenv("GIT_COMMITTER_EMAIL", "deploy@norsetinge.com")
+	os.MkdirAll(mirror, 0755)
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = mirror
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	for _, version := range []string{"v1", "v2"} {
+		os.WriteFile(filepath.Join(mirror, "index.html"), []byte(version), 0644)
+		git("add", "-A")
+		git("commit", "-q", "-m", "Deploy: "+version)
+	}
+
+	var deploys []deployer.DeployCommit
+	if code := doAPI(t, handler, "GET", "/api/v1/deploys", "", &deploys); code != http.StatusOK || len(deploys) != 2 {
+		t.Fatalf("Deploys: expected 200 with 2 deploys, got %d %+v", code, deploys)
+	}
+
+	if code := doAPI(t, handler, "POST", "/api/v1/rollback", `{}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Rollback without commit: expected 400, got %d", code)
+	}
+	if code := doAPI(t, handler, "POST", "/api/v1/rollback", `{"commit": "0000000"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Rollback to unknown commit: expected 400, got %d", code)
+	}
+
+	var status apiStatus
+	body := `{"commit": "` + deploys[1].Hash + `"}`
+	if code := doAPI(t, handler, "POST", "/api/v1/rollback", body, &status); code != http.StatusOK {
+		t.Fatalf("Rollback: expected 200, got %d", code)
+	}
+	if status.LastDeploy == nil || !status.LastDeploy.OK || status.LastDeploy.Rollback != deploys[1].Hash {
+		t.Errorf("Rollback not recorded as last deploy: %+v", status.LastDeploy)
+	}
+	if data, _ := os.ReadFile(filepath.Join(webhost, "index.html")); string(data) != "v1" {
+		t.Errorf("Webhost not rolled back, got %q", data)
+	}
+}
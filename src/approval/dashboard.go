@@ -0,0 +1,365 @@
+package approval
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"norsetinge/src/builder"
+	"norsetinge/src/common"
+	"norsetinge/src/deployer"
+)
+
+// dashboardRow is one article on the pipeline dashboard
+type dashboardRow struct {
+	ID           string
+	ApprovalPath string
+	Title        string
+	Author       string
+	Folder       string
+	Status       string
+	State        ApprovalState
+	Stage        string
+	Since        time.Time
+	TimeInState  string
+	Pending      bool
+	PublishAt    time.Time // Set while publish_at is in the future
+}
+
+// dashboardStage is a pipeline stage with its article count
+type dashboardStage struct {
+	Name  string
+	Count int
+}
+
+// dashboardData is the template data for /dashboard
+type dashboardData struct {
+	Generated  time.Time
+	Stages     []dashboardStage
+	Rows       []dashboardRow
+	LastBuild  *runResult
+	LastDeploy *runResult
+	Previews   []PreviewJob
+	Deploys    []deployer.DeployCommit // Recent deploys that can be rolled back to
+
+	LastPlan       *deployer.DeployReport  // Last dry-run
+	PendingDeletes *deployer.DeployReport  // Deploy stopped by deploy.max_deletes
+	Deploy         builder.SchedulerStatus // Running and queued build+deploy
+}
+
+// dashboardDeploys is the number of recent deploys shown on the dashboard
+const dashboardDeploys = 5
+
+// pipelineStages are the dashboard stages in workflow order (doc/project_plan.md)
+var pipelineStages = []string{"Kladde", "Modtaget", "Venter på godkendelse", "Oversætter", "Planlagt", "Publiceret", "Retur til forfatter", "Trukket tilbage"}
+
+// handleDashboard shows every article in every monitored folder with its pipeline state.
+// Approval links are only shown to a verified editor and signed for them.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authorizeEditor(w, r, ""); !ok {
+		return
+	}
+
+	rows, err := s.dashboardRows(s.verifiedEditor(r))
+	if err != nil {
+		log.Printf("Error building dashboard: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.Stage]++
+	}
+
+	data := dashboardData{Generated: time.Now(), Rows: rows}
+	for _, stage := range pipelineStages {
+		data.Stages = append(data.Stages, dashboardStage{Name: stage, Count: counts[stage]})
+	}
+
+	s.runMu.Lock()
+	data.LastBuild = s.lastBuild
+	data.LastDeploy = s.lastDeploy
+	data.LastPlan = s.lastPlan
+	data.PendingDeletes = s.pendingDeletes
+	s.runMu.Unlock()
+	data.Previews = s.PreviewJobs()
+	data.Deploy = s.scheduler.Status()
+	if deploys, err := s.Deploys(dashboardDeploys); err == nil {
+		data.Deploys = deploys
+	} // No git history (git.auto_commit off) - nothing to roll back to
+
+	tmpl := template.Must(template.New("dashboard").Funcs(template.FuncMap{
+		"formatTime":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+		"previewState": previewStateName,
+	}).Parse(dashboardTemplate))
+	if err := tmpl.Execute(w, data); err != nil {
+		log.Printf("Error rendering dashboard: %v", err)
+	}
+}
+
+// dashboardRows loads all articles from the monitored folders, longest waiting first.
+// Pending articles link to an approval page signed for editor, if one is given.
+func (s *Server) dashboardRows(editor string) ([]dashboardRow, error) {
+	if s.mover == nil {
+		return nil, fmt.Errorf("file mover not configured")
+	}
+
+	folders, err := s.mover.GetAllMonitoredFolders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitored folders: %w", err)
+	}
+
+	now := time.Now()
+	var rows []dashboardRow
+
+	for _, folder := range folders {
+		articles, err := common.LoadArticles(folder)
+		if err != nil {
+			log.Printf("Warning: Failed to load articles from %s: %v", folder, err)
+			continue
+		}
+
+		for _, article := range articles {
+			row := dashboardRow{
+				ID:     article.ID,
+				Title:  article.Title,
+				Author: article.Author,
+				Folder: filepath.Base(folder),
+				Status: article.GetCurrentStatus(),
+			}
+
+			if entry, exists := s.flow.Get(article.ID); exists {
+				if editor != "" {
+					row.ApprovalPath = s.approvalPath(entry.ID, editor)
+				}
+				row.State = entry.State
+				row.Since = entry.StateSince()
+				row.Pending = entry.State == StatePendingApproval
+			} else if info, err := os.Stat(article.FilePath); err == nil {
+				row.Since = info.ModTime() // Not in the flow - time since last edit
+			}
+
+			if article.IsScheduled(now) {
+				row.PublishAt = article.PublishAt
+			}
+
+			row.Stage = pipelineStage(row.Status, row.State)
+			if row.Stage == "Publiceret" && !row.PublishAt.IsZero() {
+				row.Stage = "Planlagt" // Approved but publish_at not reached
+			}
+			row.TimeInState = formatAge(now.Sub(row.Since))
+			rows = append(rows, row)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Since.Before(rows[j].Since)
+	})
+	return rows, nil
+}
+
+// previewStateName returns the Danish name of a preview job state
+func previewStateName(state PreviewJobState) string {
+	switch state {
+	case PreviewQueued:
+		return "i kø"
+	case PreviewBuilding:
+		return "bygger"
+	case PreviewFailed:
+		return "fejlet"
+	default:
+		return "klar"
+	}
+}
+
+// pipelineStage maps folder status and flow state to a dashboard stage
+func pipelineStage(status string, state ApprovalState) string {
+	switch state {
+	case StatePreviewBuilding, StatePendingApproval:
+		return "Venter på godkendelse"
+	case StateApproved, StateTranslating:
+		return "Oversætter"
+	case StateScheduled:
+		return "Planlagt"
+	case StateDeployedToMirror, StateDeployedToWebhost:
+		return "Publiceret"
+	case StateUnpublished:
+		return "Trukket tilbage"
+	}
+
+	switch status {
+	case "published":
+		return "Publiceret"
+	case "revision", "rejected":
+		return "Retur til forfatter"
+	case "unpublish":
+		return "Trukket tilbage"
+	case "publish", "update":
+		return "Modtaget"
+	default:
+		return "Kladde"
+	}
+}
+
+// formatAge formats a duration as a short Danish age ("5 min", "3 t", "2 d")
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "< 1 min"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d t", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d d", int(d.Hours()/24))
+	}
+}
+
+const dashboardTemplate = `
+<!DOCTYPE html>
+<html lang="da">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta http-equiv="refresh" content="60">
+    <title>Norsetinge Pipeline</title>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif;
+            max-width: 1100px;
+            margin: 40px auto;
+            padding: 20px;
+            line-height: 1.5;
+        }
+        .stages {
+            display: flex;
+            gap: 10px;
+            margin: 20px 0;
+        }
+        .stage {
+            flex: 1;
+            background: #f5f5f5;
+            border-radius: 6px;
+            padding: 12px;
+            text-align: center;
+        }
+        .stage .count { font-size: 28px; font-weight: 600; }
+        .runs {
+            display: flex;
+            gap: 15px;
+            margin: 20px 0;
+        }
+        .run {
+            flex: 1;
+            border-left: 4px solid #28a745;
+            background: #f5fff7;
+            padding: 10px 15px;
+            border-radius: 4px;
+        }
+        .run.failed { border-color: #dc3545; background: #fff5f5; }
+        .run pre { white-space: pre-wrap; font-size: 12px; margin: 5px 0 0 0; }
+        .preview-job.failed { color: #dc3545; }
+        .deploys { margin: 0 0 20px 0; font-size: 14px; }
+        .confirm { border-left: 4px solid #ff9800; background: #fff8e1; padding: 10px 15px; border-radius: 4px; margin: 0 0 20px 0; font-size: 14px; }
+        .report ul { margin: 5px 0; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 8px; border-bottom: 1px solid #ddd; font-size: 14px; }
+        th { background: #333; color: white; }
+        tr.pending { background: #fff8e1; }
+        code { font-size: 13px; }
+        a.approve { color: #28a745; font-weight: 600; }
+        .scheduled { color: #ff9800; font-size: 13px; }
+    </style>
+</head>
+<body>
+    <h1>📊 Norsetinge Pipeline</h1>
+    <p style="color: #666;">Opdateret {{formatTime .Generated}}{{with .Deploy.Running}} · 🔄 Kører: {{.Reason}} siden {{formatTime .Since}}{{end}}{{with .Deploy.Queued}} · ⏳ I kø: {{.Waiting}} anmodning(er){{end}}{{with .Deploy.Undeployed}} · 📦 Build fra {{formatTime .}} ikke deployet endnu{{end}}</p>
+
+    <div class="stages">
+        {{range .Stages}}
+        <div class="stage"><div class="count">{{.Count}}</div>{{.Name}}</div>
+        {{end}}
+    </div>
+
+    <div class="runs">
+        <div class="run{{if and .LastBuild .LastBuild.Err}} failed{{end}}">
+            <strong>🔨 Seneste build</strong><br>
+            {{with .LastBuild}}{{formatTime .Finished}} ({{.Duration}}){{if .Err}}<pre>{{.Err}}</pre>{{else}} ✓{{end}}{{with .Changes}}<br><small>{{len .Added}} nye, {{len .Changed}} ændrede, {{len .Removed}} fjernede, {{.Unchanged}} uændrede</small>{{end}}{{else}}Ingen endnu{{end}}
+        </div>
+        <div class="run{{if and .LastDeploy .LastDeploy.Err}} failed{{end}}">
+            <strong>🚀 Seneste deploy</strong><br>
+            {{with .LastDeploy}}{{formatTime .Finished}} ({{.Duration}}){{if .Err}}<pre>{{.Err}}</pre>{{else}} ✓{{end}}{{with .Rollback}}<br><small>⏪ Rollback til <code>{{.ShortHash}}</code> ({{.Subject}})</small>{{end}}{{else}}Ingen endnu{{end}}
+        </div>
+        <div class="run previews">
+            <strong>🖼️ Previews</strong><br>
+            {{range .Previews}}
+            <div class="preview-job {{.State}}">{{.Title}} ({{.ID}}): {{previewState .State}}{{if .Err}}<pre>{{.Err}}</pre>{{end}}</div>
+            {{else}}Ingen i kø{{end}}
+        </div>
+    </div>
+
+    {{with .PendingDeletes}}
+    <div class="confirm report">
+        <strong>⚠️ Deploy stoppet: {{.Deletes}} filer ville blive slettet</strong>
+        <p style="font-size: 13px;">Gennemse ændringerne og bekræft med <code>POST /api/v1/deploy</code> og <code>{"confirm_deletes": {{.Deletes}}}</code>.</p>
+        {{template "report" .}}
+    </div>
+    {{else}}{{with .LastPlan}}
+    <details class="deploys report">
+        <summary>🔍 Seneste dry-run ({{formatTime .Generated}})</summary>
+        {{template "report" .}}
+    </details>
+    {{end}}{{end}}
+
+    {{if .Deploys}}
+    <details class="deploys">
+        <summary>⏪ Deploy-historik</summary>
+        <p style="color: #666; font-size: 13px;">Rul tilbage med <code>norsetinge rollback &lt;commit&gt;</code> eller <code>POST /api/v1/rollback</code>.</p>
+        <ul>
+            {{range .Deploys}}<li><code>{{.ShortHash}}</code> {{formatTime .Time}} - {{.Subject}}</li>{{end}}
+        </ul>
+    </details>
+    {{end}}
+
+    <table>
+        <tr>
+            <th>Fase</th>
+            <th>Titel</th>
+            <th>ID</th>
+            <th>Forfatter</th>
+            <th>Status</th>
+            <th>Tilstand</th>
+            <th>Tid</th>
+            <th></th>
+        </tr>
+        {{range .Rows}}
+        <tr{{if .Pending}} class="pending"{{end}}>
+            <td>{{.Stage}}</td>
+            <td>{{.Title}}</td>
+            <td><code>{{.ID}}</code></td>
+            <td>{{.Author}}</td>
+            <td>{{.Status}} <span style="color: #999;">({{.Folder}}/)</span></td>
+            <td>{{if .State}}{{.State}}{{else}}-{{end}}{{if not .PublishAt.IsZero}}<br><span class="scheduled">⏰ {{formatTime .PublishAt}}</span>{{end}}</td>
+            <td>{{.TimeInState}}</td>
+            <td>{{if and .Pending .ApprovalPath}}<a class="approve" href="{{.ApprovalPath}}">Godkend →</a>{{end}}</td>
+        </tr>
+        {{else}}
+        <tr><td colspan="8">Ingen artikler</td></tr>
+        {{end}}
+    </table>
+</body>
+</html>
+{{define "report"}}<ul>
+    <li>Mirror: {{template "changes" .Mirror}}</li>
+    {{range .Targets}}<li>{{.Name}}: {{if .Error}}<span class="preview-job failed">{{.Error}}</span>{{else}}{{template "changes" .ChangeSet}}{{end}}</li>
+    {{end}}
+</ul>{{end}}
+{{define "changes"}}{{len .Added}} nye, {{len .Changed}} ændrede, {{len .Deleted}} slettede{{if .Deleted}}
+    <details><summary>Slettes</summary><ul>{{range .Deleted}}<li><code>{{.}}</code></li>{{end}}</ul></details>{{end}}{{end}}
+`
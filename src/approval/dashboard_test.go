@@ -0,0 +1,114 @@
+package approval
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+	"norsetinge/src/deployer"
+)
+
+func TestDashboard(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Approval: config.ApprovalConfig{
+			Editors: []config.EditorConfig{{Name: "TB", Login: "tb", Password: "hemmelig"}},
+		},
+	}
+	server := NewServer(cfg)
+
+	draftDir := filepath.Join(tmpDir, "kladde")
+	publishDir := filepath.Join(tmpDir, "udgiv")
+	os.MkdirAll(draftDir, 0755)
+	os.MkdirAll(publishDir, 0755)
+	server.SetMover(&recordingMover{folders: []string{draftDir, publishDir}})
+
+	os.WriteFile(filepath.Join(draftDir, "draft.md"), []byte("---\nid: \"#DSH001\"\ntitle: Kladde artikel\nauthor: AB\nstatus:\n  draft: 1\n---\n\nBody\n"), 0644)
+	pendingPath := filepath.Join(publishDir, "pending.md")
+	os.WriteFile(pendingPath, []byte("---\nid: \"#DSH002\"\ntitle: Venter <her>\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+
+	pending, _ := common.ParseArticle(pendingPath)
+	server.flow.Begin(pending)
+	server.flow.Transition(pending.ID, StatePendingApproval, "")
+
+	// Without hugo installed the build fails - either way the result is recorded
+	buildErr := server.BuildAndDeploy()
+
+	// A deploy stopped by deploy.max_deletes shows its dry-run
+	server.pendingDeletes = &deployer.DeployReport{
+		Mirror:  deployer.ChangeSet{Deleted: []string{"posts/gammel/index.html"}},
+		Targets: []deployer.TargetReport{{Name: "rsync deploy@norsetinge.com:/var/www", Error: "rsync dry-run failed"}},
+	}
+
+	// Signed approval links are only handed to editors
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest("GET", "/dashboard", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Anonymous dashboard: expected 401, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.SetBasicAuth("tb", "hemmelig")
+	rec = httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Kladde artikel",
+		"Venter &lt;her&gt;",
+		"#DSH001",
+		`href="/approve/DSH002?`,
+		"PendingApproval",
+		"Seneste build",
+		"Deploy stoppet: 1 filer",
+		"posts/gammel/index.html",
+		"rsync dry-run failed",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Dashboard missing %q", want)
+		}
+	}
+
+	if buildErr != nil && !strings.Contains(body, `class="run failed"`) {
+		t.Errorf("Failed build not shown on dashboard: %v", buildErr)
+	}
+}
+
+func TestPipelineStage(t *testing.T) {
+	tests := []struct {
+		status string
+		state  ApprovalState
+		want   string
+	}{
+		{"draft", "", "Kladde"},
+		{"publish", "", "Modtaget"},
+		{"publish", StatePendingApproval, "Venter på godkendelse"},
+		{"published", StateTranslating, "Oversætter"},
+		{"published", StateScheduled, "Planlagt"},
+		{"published", StateDeployedToWebhost, "Publiceret"},
+		{"revision", StateRevisionRequested, "Retur til forfatter"},
+		{"unpublish", "", "Trukket tilbage"},
+		{"unpublish", StateUnpublished, "Trukket tilbage"},
+	}
+
+	for _, tt := range tests {
+		if got := pipelineStage(tt.status, tt.state); got != tt.want {
+			t.Errorf("pipelineStage(%s, %s) = %s, want %s", tt.status, tt.state, got, tt.want)
+		}
+	}
+}
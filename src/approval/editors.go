@@ -0,0 +1,156 @@
+package approval
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+// requestEditor returns the editor identity the request itself carries: the
+// Tailscale-User-Login header set by `tailscale serve`, or basic auth.
+// The header is only trusted from loopback, where tailscale serve proxies from.
+func (s *Server) requestEditor(r *http.Request) string {
+	if login := r.Header.Get("Tailscale-User-Login"); login != "" && fromLoopback(r) {
+		return login
+	}
+
+	if user, password, ok := r.BasicAuth(); ok {
+		if editor, found := s.knownEditor(user); found && editor.Password != "" &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(editor.Password)) == 1 {
+			return editorID(editor)
+		}
+	}
+
+	return ""
+}
+
+// authorizeEditor resolves who is acting. The request identity wins over the editor
+// from a signed link or form (already verified by the caller). With approval.editors
+// configured the editor must be one of them; writes 401/403 and returns false if not.
+func (s *Server) authorizeEditor(w http.ResponseWriter, r *http.Request, signedEditor string) (string, bool) {
+	editor := s.requestEditor(r)
+	if editor == "" {
+		editor = signedEditor
+	}
+
+	if len(s.cfg.Approval.Editors) == 0 {
+		return editor, true // No editors configured - record whatever identity we have
+	}
+
+	if editor == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Norsetinge"`)
+		http.Error(w, "Editor login required", http.StatusUnauthorized)
+		return "", false
+	}
+
+	known, found := s.knownEditor(editor)
+	if !found {
+		http.Error(w, "Unknown editor: "+editor, http.StatusForbidden)
+		return "", false
+	}
+
+	return editorID(known), true
+}
+
+// apiEditor authorizes a JSON API action. The API has no signed links, so it needs a
+// configured editor logged in through Tailscale or basic auth; without approval.editors
+// API actions are refused. Writes a JSON 401/403 and returns false if not authorized.
+func (s *Server) apiEditor(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if len(s.cfg.Approval.Editors) == 0 {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "API actions require approval.editors to be configured"})
+		return "", false
+	}
+
+	if s.requestEditor(r) == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Norsetinge"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "editor login required"})
+		return "", false
+	}
+
+	editor := s.verifiedEditor(r)
+	if editor == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "unknown editor: " + s.requestEditor(r)})
+		return "", false
+	}
+	return editor, true
+}
+
+// verifiedEditor returns the configured editor the request itself identifies, or ""
+// without approval.editors. Only such a request is handed signed approval links.
+func (s *Server) verifiedEditor(r *http.Request) string {
+	if editor, found := s.knownEditor(s.requestEditor(r)); found {
+		return editorID(editor)
+	}
+	return ""
+}
+
+// knownEditor finds a configured editor by login or email
+func (s *Server) knownEditor(identity string) (config.EditorConfig, bool) {
+	for _, editor := range s.cfg.Approval.Editors {
+		if (editor.Login != "" && strings.EqualFold(editor.Login, identity)) ||
+			(editor.Email != "" && strings.EqualFold(editor.Email, identity)) {
+			return editor, true
+		}
+	}
+	return config.EditorConfig{}, false
+}
+
+// authorAddress returns the email address of an article's author: from email.authors,
+// else the editor with that name, else ""
+func (s *Server) authorAddress(author string) string {
+	for name, address := range s.cfg.Email.Authors {
+		if strings.EqualFold(name, author) {
+			return address
+		}
+	}
+	for _, editor := range s.cfg.Approval.Editors {
+		if editor.Email != "" && strings.EqualFold(editor.Name, author) {
+			return editor.Email
+		}
+	}
+	return ""
+}
+
+// requiredApprovals returns the approvals an article needs: the highest number from
+// any policy matching one of its tags, or one
+func (s *Server) requiredApprovals(article *common.Article) int {
+	required := 1
+	for _, policy := range s.cfg.Approval.Policies {
+		for _, tag := range article.Tags {
+			if strings.EqualFold(tag, policy.Tag) && policy.Approvals > required {
+				required = policy.Approvals
+			}
+		}
+	}
+	return required
+}
+
+// editorID is the identity recorded for an editor: login, or email if no login is set
+func editorID(editor config.EditorConfig) string {
+	if editor.Login != "" {
+		return editor.Login
+	}
+	return editor.Email
+}
+
+// editorName returns the editor for log messages
+func editorName(editor string) string {
+	if editor == "" {
+		return "unknown editor"
+	}
+	return editor
+}
+
+// fromLoopback reports whether the request comes from this machine
+func fromLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,165 @@
+package approval
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func newEditorTestServer(t *testing.T) *Server {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Approval: config.ApprovalConfig{
+			SigningKey: "test-key",
+			Editors: []config.EditorConfig{
+				{Name: "TB", Login: "tb@github", Email: "tb@example.com"},
+				{Name: "AB", Login: "ab", Password: "hemmelig"},
+			},
+			Policies: []config.ApprovalPolicy{{Tag: "Politik", Approvals: 2}},
+		},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+
+	for id, tags := range map[string]string{"#EDT001": "[nyheder]", "#EDT002": "[nyheder, politik]"} {
+		path := filepath.Join(tmpDir, strings.TrimPrefix(id, "#")+".md")
+		os.WriteFile(path, []byte("---\nid: \""+id+"\"\ntitle: Editor\nauthor: TB\ntags: "+tags+"\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+		article, _ := common.ParseArticle(path)
+
+		// RequestApproval without the preview build (no hugo in tests)
+		server.flow.Begin(article)
+		server.flow.Update(id, func(p *PendingArticle) { p.RequiredApprovals = server.requiredApprovals(article) })
+		server.flow.Transition(id, StatePendingApproval, "")
+	}
+
+	return server
+}
+
+func TestEditorIdentity(t *testing.T) {
+	server := newEditorTestServer(t)
+
+	tests := []struct {
+		name   string
+		setup  func(r *http.Request)
+		editor string
+		code   int
+	}{
+		{"tailscale serve", func(r *http.Request) {
+			r.RemoteAddr = "127.0.0.1:40000"
+			r.Header.Set("Tailscale-User-Login", "tb@github")
+		}, "tb@github", http.StatusOK},
+		{"tailscale header from tailnet peer", func(r *http.Request) {
+			r.Header.Set("Tailscale-User-Login", "tb@github")
+		}, "", http.StatusUnauthorized},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("ab", "hemmelig") }, "ab", http.StatusOK},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("ab", "forkert") }, "", http.StatusUnauthorized},
+		{"unknown tailscale user", func(r *http.Request) {
+			r.RemoteAddr = "[::1]:40000"
+			r.Header.Set("Tailscale-User-Login", "mallory@github")
+		}, "", http.StatusForbidden},
+		{"anonymous", func(r *http.Request) {}, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", server.approvalPath("#EDT001", ""), nil)
+			tt.setup(req)
+
+			rec := httptest.NewRecorder()
+			editor, ok := server.authorizeEditor(rec, req, req.URL.Query().Get("editor"))
+			if editor != tt.editor || ok != (tt.code == http.StatusOK) || rec.Code != tt.code {
+				t.Errorf("authorizeEditor() = (%q, %v) with %d, want %q with %d", editor, ok, rec.Code, tt.editor, tt.code)
+			}
+		})
+	}
+
+	// A personal link identifies the editor it was sent to
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest("GET", server.approvalPath("#EDT001", "tb@github"), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Personal link: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `name="editor" value="tb@github"`) {
+		t.Error("Approval page forms do not carry the editor")
+	}
+
+	// ... and the form posted from that page acts as that editor
+	form := url.Values{"editor": {"tb@github"}, "csrf_token": {server.signer.CSRFToken("#EDT001", "tb@github")}}
+	req := httptest.NewRequest("POST", "/action/reject/EDT001", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Reject: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	entry, _ := server.flow.Get("#EDT001")
+	if last := entry.History[len(entry.History)-1]; last.State != StateRejected || last.Editor != "tb@github" {
+		t.Errorf("Expected rejection by tb@github in history, got %+v", last)
+	}
+}
+
+func TestMultiApproverPolicy(t *testing.T) {
+	server := newEditorTestServer(t)
+
+	if entry, _ := server.flow.Get("#EDT001"); entry.Required() != 1 {
+		t.Errorf("Untagged article: expected 1 required approval, got %d", entry.Required())
+	}
+	if entry, _ := server.flow.Get("#EDT002"); entry.Required() != 2 {
+		t.Fatalf("Article tagged politik: expected 2 required approvals, got %d", entry.Required())
+	}
+
+	complete, err := server.approve("#EDT002", "tb@github", false)
+	if err != nil || complete {
+		t.Fatalf("First approval: expected incomplete, got (%v, %v)", complete, err)
+	}
+
+	// The approval page shows who has approved so far
+	req := httptest.NewRequest("GET", server.approvalPath("#EDT002", ""), nil)
+	req.SetBasicAuth("ab", "hemmelig")
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, req)
+	for _, want := range []string{"Godkendelser: 1 af 2", "tb@github", "Redaktør:</strong> ab"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Approval page missing %q", want)
+		}
+	}
+
+	// The same editor cannot approve twice
+	if _, err := server.approve("#EDT002", "tb@github", false); actionErrorStatus(err) != http.StatusConflict {
+		t.Errorf("Repeated approval: expected conflict, got %v", err)
+	}
+
+	complete, err = server.approve("#EDT002", "ab", false)
+	if err != nil || !complete {
+		t.Fatalf("Second approval: expected complete, got (%v, %v)", complete, err)
+	}
+
+	entry, _ := server.flow.Get("#EDT002")
+	if entry.State != StateApproved {
+		t.Errorf("Expected Approved, got %s", entry.State)
+	}
+	if len(entry.Approvals) != 2 || entry.Approvals[0].Editor != "tb@github" || entry.Approvals[1].Editor != "ab" {
+		t.Errorf("Unexpected approvals: %+v", entry.Approvals)
+	}
+
+	// Approvals are journaled with the article
+	flow, _ := NewPublishFlow(server.getPublishFlowPath())
+	if reloaded, _ := flow.Get("#EDT002"); reloaded.RequiredApprovals != 2 || len(reloaded.Approvals) != 2 {
+		t.Errorf("Approvals not persisted: %+v", reloaded)
+	}
+}
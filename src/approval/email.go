@@ -0,0 +1,243 @@
+package approval
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+// excerptLength is the maximum number of characters of article text in the approval email
+const excerptLength = 400
+
+// EmailSender sends approval emails via SMTP
+type EmailSender struct {
+	cfg *config.Config
+}
+
+// NewEmailSender creates a new email sender
+func NewEmailSender(cfg *config.Config) *EmailSender {
+	return &EmailSender{cfg: cfg}
+}
+
+// SendApprovalEmail sends an HTML approval email with title, author, excerpt and the signed approval link.
+// The subject carries the article ID as "[Norsetinge #ABC123]" so replies can be matched, and the
+// Message-ID carries replyToken, which a reply must answer to be applied.
+func (e *EmailSender) SendApprovalEmail(article *common.Article, to, approvalURL, replyToken string) error {
+	if !e.cfg.Email.Enabled {
+		log.Printf("Email notifications disabled")
+		return nil
+	}
+
+	var body bytes.Buffer
+	tmpl := template.Must(template.New("email").Parse(approvalEmailTemplate))
+	if err := tmpl.Execute(&body, struct {
+		Article     *common.Article
+		Excerpt     string
+		ApprovalURL string
+	}{
+		Article:     article,
+		Excerpt:     excerpt(article, excerptLength),
+		ApprovalURL: approvalURL,
+	}); err != nil {
+		return fmt.Errorf("failed to render approval email: %w", err)
+	}
+
+	subject := fmt.Sprintf("[Norsetinge %s] Godkend: %s", article.ID, article.Title)
+	if err := e.send(to, subject, body.String(), replyToken); err != nil {
+		return err
+	}
+
+	log.Printf("📧 Approval email sent to %s: %s", to, article.Title)
+	return nil
+}
+
+// SendRevisionEmail sends the editor's requested changes to the author
+func (e *EmailSender) SendRevisionEmail(article *common.Article, to, comments string) error {
+	if !e.cfg.Email.Enabled {
+		return nil
+	}
+
+	var body bytes.Buffer
+	tmpl := template.Must(template.New("revision").Parse(revisionEmailTemplate))
+	if err := tmpl.Execute(&body, struct {
+		Article  *common.Article
+		Comments string
+	}{
+		Article:  article,
+		Comments: comments,
+	}); err != nil {
+		return fmt.Errorf("failed to render revision email: %w", err)
+	}
+
+	subject := fmt.Sprintf("[Norsetinge %s] Rettelser: %s", article.ID, article.Title)
+	if err := e.send(to, subject, body.String(), ""); err != nil {
+		return err
+	}
+
+	log.Printf("📧 Revision request sent to %s: %s", to, article.Title)
+	return nil
+}
+
+// send delivers an HTML message, with replyToken (if any) in its Message-ID. Port 465
+// uses implicit TLS, other ports upgrade with STARTTLS when the server offers it.
+func (e *EmailSender) send(to, subject, htmlBody, replyToken string) error {
+	msg, err := e.buildMessage(to, subject, htmlBody, replyToken)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(e.cfg.Email.SMTPHost, fmt.Sprintf("%d", e.cfg.Email.SMTPPort))
+
+	var auth smtp.Auth
+	if e.cfg.Email.SMTPUser != "" {
+		auth = smtp.PlainAuth("", e.cfg.Email.SMTPUser, e.cfg.Email.SMTPPassword, e.cfg.Email.SMTPHost)
+	}
+
+	if e.cfg.Email.SMTPPort != 465 {
+		if err := smtp.SendMail(addr, auth, e.cfg.Email.FromAddress, []string{to}, msg); err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: e.cfg.Email.SMTPHost})
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Email.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(e.cfg.Email.FromAddress); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage creates a MIME message with a quoted-printable HTML body
+func (e *EmailSender) buildMessage(to, subject, htmlBody, replyToken string) ([]byte, error) {
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: Norsetinge <%s>\r\n", e.cfg.Email.FromAddress)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %s\r\n", e.messageID(replyToken))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	msg.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	if _, err := qp.Write([]byte(htmlBody)); err != nil {
+		return nil, fmt.Errorf("failed to encode email body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode email body: %w", err)
+	}
+
+	return msg.Bytes(), nil
+}
+
+// messageID creates a unique Message-ID in the sender's domain:
+// <reply.{token}.{random}@domain> with a reply token, else <{time}.{random}@domain>
+func (e *EmailSender) messageID(replyToken string) string {
+	domain := "norsetinge"
+	if at := strings.LastIndex(e.cfg.Email.FromAddress, "@"); at >= 0 {
+		domain = e.cfg.Email.FromAddress[at+1:]
+	}
+
+	random := make([]byte, 8)
+	rand.Read(random)
+	if replyToken != "" {
+		return fmt.Sprintf("<reply.%s.%s@%s>", replyToken, hex.EncodeToString(random), domain)
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// excerpt returns the description, or the start of the content cut at a word boundary
+func excerpt(article *common.Article, maxLen int) string {
+	if article.Description != "" {
+		return article.Description
+	}
+
+	text := strings.Join(strings.Fields(article.Content), " ")
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+
+	cut := string(runes[:maxLen])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return cut + "…"
+}
+
+const approvalEmailTemplate = `<!DOCTYPE html>
+<html lang="da">
+<head><meta charset="UTF-8"><title>{{.Article.Title}}</title></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px; line-height: 1.6; color: #333;">
+    <h1 style="font-size: 22px; border-bottom: 2px solid #333; padding-bottom: 10px;">📰 Artikel til Godkendelse</h1>
+    <p><strong>Titel:</strong> {{.Article.Title}}<br>
+    <strong>Forfatter:</strong> {{.Article.Author}}<br>
+    <strong>ID:</strong> {{.Article.ID}}</p>
+    <blockquote style="background: #f5f5f5; border-left: 4px solid #ccc; margin: 20px 0; padding: 15px;">{{.Excerpt}}</blockquote>
+    <p style="text-align: center; margin: 30px 0;">
+        <a href="{{.ApprovalURL}}" style="background: #28a745; color: white; padding: 15px 40px; border-radius: 6px; text-decoration: none; font-weight: 600;">Åbn godkendelsessiden</a>
+    </p>
+    <p style="color: #666; font-size: 13px;">Godkendelsessiden viser det fulde preview med knapper til at godkende, afvise eller foreslå rettelser.</p>
+</body>
+</html>
+`
+
+const revisionEmailTemplate = `<!DOCTYPE html>
+<html lang="da">
+<head><meta charset="UTF-8"><title>{{.Article.Title}}</title></head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px; line-height: 1.6; color: #333;">
+    <h1 style="font-size: 22px; border-bottom: 2px solid #333; padding-bottom: 10px;">✏️ Rettelser til din artikel</h1>
+    <p><strong>Titel:</strong> {{.Article.Title}}<br>
+    <strong>ID:</strong> {{.Article.ID}}</p>
+    <p>Redaktionen har bedt om rettelser:</p>
+    <blockquote style="background: #f5f5f5; border-left: 4px solid #ccc; margin: 20px 0; padding: 15px; white-space: pre-wrap;">{{.Comments}}</blockquote>
+    <p style="color: #666; font-size: 13px;">Artiklen er flyttet til afventer-rettelser/. Ret den, og sæt status til publish igen for at sende den til godkendelse.</p>
+</body>
+</html>
+`
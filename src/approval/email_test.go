@@ -0,0 +1,251 @@
+package approval
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+// smtpStub is a minimal in-process SMTP server that accepts one message
+type smtpStub struct {
+	listener net.Listener
+	from     string
+	rcpt     []string
+	data     chan string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start SMTP stub: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	stub := &smtpStub{listener: listener, data: make(chan string, 1)}
+	go stub.serve()
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			s.data <- data.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSendApprovalEmail(t *testing.T) {
+	stub := newSMTPStub(t)
+
+	cfg := &config.Config{
+		Email: config.EmailConfig{
+			Enabled:           true,
+			SMTPHost:          "127.0.0.1",
+			SMTPPort:          stub.port(),
+			SMTPUser:          "publisher@norsetinge.com",
+			SMTPPassword:      "secret",
+			FromAddress:       "publisher@norsetinge.com",
+			ApprovalRecipient: "editor@example.com",
+		},
+		Approval: config.ApprovalConfig{
+			TailscaleHostname: "norsetinge.tailnet.ts.net",
+		},
+	}
+
+	article := &common.Article{
+		ID:      "#ABC123",
+		Title:   "Blåbærgrød & <kode>",
+		Author:  "TB",
+		Content: strings.Repeat("Lorem ipsum dolor sit amet. ", 40),
+	}
+
+	if err := NewEmailSender(cfg).SendApprovalEmail(article, "editor@example.com", "https://norsetinge.tailnet.ts.net/approve/ABC123?exp=1&sig=00", "1759485600.00ff"); err != nil {
+		t.Fatalf("SendApprovalEmail failed: %v", err)
+	}
+
+	data := <-stub.data
+
+	if stub.from != "publisher@norsetinge.com" {
+		t.Errorf("Unexpected MAIL FROM: %s", stub.from)
+	}
+	if len(stub.rcpt) != 1 || stub.rcpt[0] != "editor@example.com" {
+		t.Errorf("Unexpected RCPT TO: %v", stub.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Failed to decode subject: %v", err)
+	}
+	if !strings.HasPrefix(subject, "[Norsetinge #ABC123]") || !strings.Contains(subject, "Blåbærgrød") {
+		t.Errorf("Unexpected subject: %s", subject)
+	}
+
+	// Replies answer this Message-ID, so it carries the reply token
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<reply.1759485600.00ff.") || !strings.HasSuffix(id, "@norsetinge.com>") {
+		t.Errorf("Unexpected Message-ID: %s", id)
+	}
+
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected HTML email, got %s", msg.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+
+	for _, want := range []string{
+		"https://norsetinge.tailnet.ts.net/approve/ABC123?exp=1&amp;sig=00",
+		"Blåbærgrød &amp; &lt;kode&gt;",
+		"Forfatter:</strong> TB",
+		"Lorem ipsum dolor sit amet.",
+		"…",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Email body missing %q", want)
+		}
+	}
+}
+
+func TestReviseEmailsAuthor(t *testing.T) {
+	stub := newSMTPStub(t)
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Email: config.EmailConfig{
+			Enabled:           true,
+			SMTPHost:          "127.0.0.1",
+			SMTPPort:          stub.port(),
+			FromAddress:       "publisher@norsetinge.com",
+			ApprovalRecipient: "editor@example.com",
+			Authors:           map[string]string{"TB (twisted brain)": "tb@example.com"},
+		},
+		Approval: config.ApprovalConfig{
+			Editors: []config.EditorConfig{{Name: "AB", Login: "ab", Email: "ab@example.com"}},
+		},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+
+	// Authors are found in email.authors, then among the editors by name
+	for author, want := range map[string]string{"tb (Twisted Brain)": "tb@example.com", "AB": "ab@example.com", "XY": ""} {
+		if got := server.authorAddress(author); got != want {
+			t.Errorf("authorAddress(%q) = %q, want %q", author, got, want)
+		}
+	}
+
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#REV002\"\ntitle: Revise me\nauthor: TB (twisted brain)\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	if err := server.revise(article.ID, "ab", "Uddyb <afsnit> 2"); err != nil {
+		t.Fatalf("revise failed: %v", err)
+	}
+
+	data := <-stub.data
+	if len(stub.rcpt) != 1 || stub.rcpt[0] != "tb@example.com" {
+		t.Errorf("Expected revision email to the author, got RCPT TO %v", stub.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if !strings.HasPrefix(subject, "[Norsetinge #REV002] Rettelser:") {
+		t.Errorf("Unexpected subject: %s", subject)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if !strings.Contains(string(body), "Uddyb &lt;afsnit&gt; 2") {
+		t.Errorf("Revision email missing the comments: %s", body)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	article := &common.Article{Content: "Første linje\n\nAnden   linje med ord"}
+
+	if got := excerpt(article, 100); got != "Første linje Anden linje med ord" {
+		t.Errorf("Unexpected excerpt: %q", got)
+	}
+	if got := excerpt(article, 20); got != "Første linje Anden…" {
+		t.Errorf("Expected cut at word boundary, got %q", got)
+	}
+
+	article.Description = "Beskrivelse"
+	if got := excerpt(article, 5); got != "Beskrivelse" {
+		t.Errorf("Expected description, got %q", got)
+	}
+}
@@ -0,0 +1,336 @@
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"norsetinge/src/common"
+)
+
+// ApprovalState is the workflow state of an article in the publish flow
+type ApprovalState string
+
+const (
+	StateIDGenerated       ApprovalState = "IDGenerated"
+	StatePreviewBuilding   ApprovalState = "PreviewBuilding"
+	StatePendingApproval   ApprovalState = "PendingApproval"
+	StateApproved          ApprovalState = "Approved"
+	StateTranslating       ApprovalState = "Translating"
+	StateScheduled         ApprovalState = "Scheduled" // Approved, waiting for publish_at
+	StateDeployedToMirror  ApprovalState = "DeployedToMirror"
+	StateDeployedToWebhost ApprovalState = "DeployedToWebhost"
+	StateRejected          ApprovalState = "Rejected"
+	StateRevisionRequested ApprovalState = "RevisionRequested"
+	StateUnpublished       ApprovalState = "Unpublished" // Taken down, tombstone at the old URL
+)
+
+// allowedTransitions lists the valid next states for each state.
+// A new approval cycle (Begin) may start from IDGenerated (failed attempt)
+// or from any state without outgoing transitions here.
+var allowedTransitions = map[ApprovalState][]ApprovalState{
+	StateIDGenerated:       {StatePreviewBuilding},
+	StatePreviewBuilding:   {StatePendingApproval, StateIDGenerated},
+	StatePendingApproval:   {StateApproved, StateRejected, StateRevisionRequested},
+	StateApproved:          {StateTranslating, StateScheduled, StateDeployedToMirror},
+	StateTranslating:       {StateScheduled, StateDeployedToMirror},
+	StateScheduled:         {StateDeployedToMirror},
+	StateDeployedToMirror:  {StateDeployedToWebhost},
+	StateDeployedToWebhost: {},
+	StateRejected:          {},
+	StateRevisionRequested: {},
+	StateUnpublished:       {},
+}
+
+// inFlightStates are states where a new approval request must not restart the flow
+var inFlightStates = map[ApprovalState]bool{
+	StatePreviewBuilding: true,
+	StatePendingApproval: true,
+	StateApproved:        true,
+	StateTranslating:     true,
+	StateScheduled:       true,
+}
+
+var (
+	errNotFound          = errors.New("article not found")
+	errInFlight          = errors.New("article is already in the publish flow")
+	errInvalidTransition = errors.New("invalid state transition")
+	errAlreadyApproved   = errors.New("editor has already approved this article")
+)
+
+// StateChange is one timestamped entry in an article's state history
+type StateChange struct {
+	State     ApprovalState `json:"state"`
+	Timestamp time.Time     `json:"timestamp"`
+	Editor    string        `json:"editor,omitempty"` // Who made the decision, for approve/reject/revise
+	Note      string        `json:"note,omitempty"`
+}
+
+// Approval is one editor's approval of an article in the current cycle
+type Approval struct {
+	Editor    string    `json:"editor"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PublishFlow is the persistent state machine for all articles in the workflow.
+// Every change is written to publish-flow.json, the single source of truth.
+type PublishFlow struct {
+	path     string
+	mu       sync.RWMutex
+	articles map[string]*PendingArticle
+}
+
+// publishFlowFile is the on-disk journal format
+type publishFlowFile struct {
+	Articles map[string]*PendingArticle `json:"articles"`
+}
+
+// NewPublishFlow loads the journal at path, or starts empty if it does not exist
+func NewPublishFlow(path string) (*PublishFlow, error) {
+	f := &PublishFlow{
+		path:     path,
+		articles: make(map[string]*PendingArticle),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return f, fmt.Errorf("failed to read publish flow: %w", err)
+	}
+
+	var file publishFlowFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return f, fmt.Errorf("failed to parse publish flow: %w", err)
+	}
+	if file.Articles != nil {
+		f.articles = file.Articles
+	}
+
+	log.Printf("Loaded %d articles from %s", len(f.articles), path)
+	return f, nil
+}
+
+// Get returns a copy of the flow entry for an article
+func (f *PublishFlow) Get(id string) (*PendingArticle, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entry, exists := f.articles[id]
+	if !exists {
+		return nil, false
+	}
+	return entry.clone(), true
+}
+
+// InState returns copies of all entries in one of the given states, oldest first
+func (f *PublishFlow) InState(states ...ApprovalState) []*PendingArticle {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var result []*PendingArticle
+	for _, entry := range f.articles {
+		for _, state := range states {
+			if entry.State == state {
+				result = append(result, entry.clone())
+				break
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StateSince().Before(result[j].StateSince())
+	})
+	return result
+}
+
+// Begin starts a new approval cycle: IDGenerated followed by PreviewBuilding.
+// Returns errInFlight if the article is already being processed.
+func (f *PublishFlow) Begin(article *common.Article) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.articles[article.ID]
+	if !exists {
+		entry = &PendingArticle{ID: article.ID}
+		f.articles[article.ID] = entry
+	} else if inFlightStates[entry.State] {
+		return fmt.Errorf("%w (state: %s)", errInFlight, entry.State)
+	}
+
+	entry.Article = article.Clone()
+	entry.PreviewPath = ""
+	entry.NotificationSent = false
+	entry.TranslationsDone = false
+	entry.Approvals = nil
+	entry.record(StateIDGenerated, "", "")
+	entry.record(StatePreviewBuilding, "", "")
+
+	return f.saveNoLock()
+}
+
+// Transition moves an article to a new state if the state machine allows it
+func (f *PublishFlow) Transition(id string, to ApprovalState, note string) error {
+	return f.TransitionBy(id, to, "", note)
+}
+
+// TransitionBy is Transition for a decision by an editor, who is recorded in the history
+func (f *PublishFlow) TransitionBy(id string, to ApprovalState, editor, note string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.articles[id]
+	if !exists {
+		return errNotFound
+	}
+
+	if !canTransition(entry.State, to) {
+		return fmt.Errorf("%w: %s → %s", errInvalidTransition, entry.State, to)
+	}
+
+	entry.record(to, editor, note)
+	return f.saveNoLock()
+}
+
+// Approve records an editor's approval. The article moves to Approved once it has
+// the required number of approvals from different editors; complete reports whether it did.
+func (f *PublishFlow) Approve(id, editor string) (complete bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.articles[id]
+	if !exists {
+		return false, errNotFound
+	}
+
+	if entry.State != StatePendingApproval {
+		return false, fmt.Errorf("%w: %s → %s", errInvalidTransition, entry.State, StateApproved)
+	}
+
+	for _, approval := range entry.Approvals {
+		if approval.Editor == editor {
+			return false, errAlreadyApproved
+		}
+	}
+
+	entry.Approvals = append(entry.Approvals, Approval{Editor: editor, Timestamp: time.Now()})
+	if len(entry.Approvals) >= entry.Required() {
+		entry.record(StateApproved, editor, "")
+		complete = true
+	}
+
+	return complete, f.saveNoLock()
+}
+
+// Unpublish records that an article was taken down. Articles published before the
+// flow existed get an entry. Returns false if it is already unpublished.
+func (f *PublishFlow) Unpublish(article *common.Article, note string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.articles[article.ID]
+	if !exists {
+		entry = &PendingArticle{ID: article.ID}
+		f.articles[article.ID] = entry
+	} else if entry.State == StateUnpublished {
+		return false, nil
+	}
+
+	entry.Article = article.Clone()
+	entry.record(StateUnpublished, "", note)
+	return true, f.saveNoLock()
+}
+
+// Update applies fn to an article's entry and persists the result
+func (f *PublishFlow) Update(id string, fn func(entry *PendingArticle)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, exists := f.articles[id]
+	if !exists {
+		return errNotFound
+	}
+
+	fn(entry)
+	return f.saveNoLock()
+}
+
+// saveNoLock writes the journal atomically (assumes caller holds lock)
+func (f *PublishFlow) saveNoLock() error {
+	data, err := json.MarshalIndent(publishFlowFile{Articles: f.articles}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal publish flow: %w", err)
+	}
+
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create publish flow directory: %w", err)
+		}
+	}
+
+	// Write to temp file and rename so a crash never leaves a half-written journal
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write publish flow: %w", err)
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("failed to replace publish flow: %w", err)
+	}
+
+	return nil
+}
+
+// canTransition reports whether the state machine allows from → to
+func canTransition(from, to ApprovalState) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a deep copy of the entry. The flow hands out copies only, so callers
+// can change them (e.g. the article's status) without racing saveNoLock.
+func (p *PendingArticle) clone() *PendingArticle {
+	copied := *p
+	copied.Article = p.Article.Clone()
+	copied.History = slices.Clone(p.History)
+	copied.Approvals = slices.Clone(p.Approvals)
+	return &copied
+}
+
+// record appends a state change to the history and makes it current
+func (p *PendingArticle) record(state ApprovalState, editor, note string) {
+	p.State = state
+	p.History = append(p.History, StateChange{
+		State:     state,
+		Timestamp: time.Now(),
+		Editor:    editor,
+		Note:      note,
+	})
+}
+
+// Required returns the number of approvals needed to publish (at least one)
+func (p *PendingArticle) Required() int {
+	if p.RequiredApprovals < 1 {
+		return 1
+	}
+	return p.RequiredApprovals
+}
+
+// StateSince returns when the article entered its current state
+func (p *PendingArticle) StateSince() time.Time {
+	if len(p.History) == 0 {
+		return time.Time{}
+	}
+	return p.History[len(p.History)-1].Timestamp
+}
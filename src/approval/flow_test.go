@@ -0,0 +1,425 @@
+package approval
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestPublishFlowTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "publish-flow.json")
+
+	flow, err := NewPublishFlow(path)
+	if err != nil {
+		t.Fatalf("NewPublishFlow failed: %v", err)
+	}
+
+	article := &common.Article{ID: "#FLOW01", Title: "Flow", Author: "TB"}
+	if err := flow.Begin(article); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	// A second request while the preview builds must not restart the flow
+	if err := flow.Begin(article); !errors.Is(err, errInFlight) {
+		t.Errorf("Expected errInFlight, got %v", err)
+	}
+
+	if err := flow.Transition(article.ID, StatePendingApproval, ""); err != nil {
+		t.Fatalf("Transition to PendingApproval failed: %v", err)
+	}
+
+	if err := flow.Transition(article.ID, StateDeployedToWebhost, ""); !errors.Is(err, errInvalidTransition) {
+		t.Errorf("Expected errInvalidTransition, got %v", err)
+	}
+
+	if err := flow.Transition(article.ID, StateApproved, "test"); err != nil {
+		t.Fatalf("Transition to Approved failed: %v", err)
+	}
+
+	// Reload from disk - the journal is the source of truth
+	reloaded, err := NewPublishFlow(path)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	entry, exists := reloaded.Get(article.ID)
+	if !exists {
+		t.Fatal("Article missing from reloaded journal")
+	}
+
+	if entry.State != StateApproved {
+		t.Errorf("Expected state Approved, got %s", entry.State)
+	}
+
+	expected := []ApprovalState{StateIDGenerated, StatePreviewBuilding, StatePendingApproval, StateApproved}
+	if len(entry.History) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %d", len(expected), len(entry.History))
+	}
+	for i, state := range expected {
+		if entry.History[i].State != state {
+			t.Errorf("History[%d]: expected %s, got %s", i, state, entry.History[i].State)
+		}
+		if entry.History[i].Timestamp.IsZero() {
+			t.Errorf("History[%d] has no timestamp", i)
+		}
+	}
+}
+
+func TestPublishFlowReturnsCopies(t *testing.T) {
+	flow, _ := NewPublishFlow(filepath.Join(t.TempDir(), "publish-flow.json"))
+
+	article := &common.Article{ID: "#FLOW02", Title: "Flow", Author: "TB", Tags: []string{"nyt"}}
+	flow.Begin(article)
+	article.Title = "Changed by the caller"
+
+	// Callers change their copy outside the flow's lock; the flow must not see it
+	entry, _ := flow.Get("#FLOW02")
+	entry.Article.UpdateStatus("rejected")
+	entry.Article.Tags[0] = "ændret"
+	entry.History[0].Note = "ændret"
+
+	again := flow.InState(StatePreviewBuilding)[0]
+	if again.Article.Title != "Flow" || again.Article.Status.Rejected != 0 || again.Article.Tags[0] != "nyt" || again.History[0].Note != "" {
+		t.Errorf("Flow entry changed through a copy: %+v", again.Article)
+	}
+}
+
+func TestNewServerResumesFromJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{
+			BasePath: tmpDir,
+		},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+
+	// Article that was approved right before a crash - status not yet written
+	approvedPath := filepath.Join(tmpDir, "approved.md")
+	os.WriteFile(approvedPath, []byte("---\nid: \"#RES002\"\ntitle: Approved\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	approved, err := common.ParseArticle(approvedPath)
+	if err != nil {
+		t.Fatalf("Failed to parse article: %v", err)
+	}
+
+	flow, _ := NewPublishFlow(filepath.Join(tmpDir, "publish-flow.json"))
+
+	pending := &common.Article{ID: "#RES001", Title: "Pending", Author: "TB"}
+	flow.Begin(pending)
+	flow.Transition(pending.ID, StatePendingApproval, "")
+
+	flow.Begin(approved)
+	flow.Transition(approved.ID, StatePendingApproval, "")
+	flow.Transition(approved.ID, StateApproved, "")
+
+	mover := &recordingMover{}
+	server := NewServer(cfg)
+	server.SetMover(mover)
+	server.Resume()
+
+	// Pending article is still available for approval
+	rec := httptest.NewRecorder()
+	server.handleApproval(rec, httptest.NewRequest("GET", "/approve/res001?"+server.signer.SignedQuery("#RES001", ""), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected approval page for resumed article, got %d", rec.Code)
+	}
+
+	// Approved article gets its status written and is moved to the published folder
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if moved := mover.Moved(); len(moved) > 0 {
+			if len(moved) != 1 || moved[0] != "published" {
+				t.Errorf("Expected one move with status published, got %v", moved)
+			}
+			if article, err := common.ParseArticle(approvedPath); err != nil || article.GetCurrentStatus() != "published" {
+				t.Errorf("Expected resumed article to have status published, got %v (%v)", article, err)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Approved article was not resumed and moved")
+}
+
+func TestNewServerDoesNotResume(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+
+	approvedPath := filepath.Join(tmpDir, "approved.md")
+	os.WriteFile(approvedPath, []byte("---\nid: \"#RES003\"\ntitle: Approved\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	approved, err := common.ParseArticle(approvedPath)
+	if err != nil {
+		t.Fatalf("Failed to parse article: %v", err)
+	}
+
+	flow, _ := NewPublishFlow(filepath.Join(tmpDir, "publish-flow.json"))
+	flow.Begin(approved)
+	flow.Transition(approved.ID, StatePendingApproval, "")
+	flow.Transition(approved.ID, StateApproved, "")
+
+	// Without Resume the approved article is left alone until the mover is set
+	NewServer(cfg)
+	time.Sleep(100 * time.Millisecond)
+	if article, err := common.ParseArticle(approvedPath); err != nil || article.GetCurrentStatus() == "published" {
+		t.Errorf("Expected NewServer not to publish the approved article, got %v (%v)", article, err)
+	}
+}
+
+func TestRejectAction(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+	server := NewServer(cfg)
+
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#REJ001\"\ntitle: Reject me\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	rec := httptest.NewRecorder()
+	server.handleReject(rec, actionRequest(server, "/action/reject/REJ001", url.Values{}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	entry, _ := server.flow.Get(article.ID)
+	if entry.State != StateRejected {
+		t.Errorf("Expected state Rejected, got %s", entry.State)
+	}
+
+	parsed, _ := common.ParseArticle(articlePath)
+	if parsed.GetCurrentStatus() != "rejected" {
+		t.Errorf("Expected status rejected, got %s", parsed.GetCurrentStatus())
+	}
+
+	// A second click must not act twice
+	rec = httptest.NewRecorder()
+	server.handleReject(rec, actionRequest(server, "/action/reject/REJ001", url.Values{}))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for repeated action, got %d", rec.Code)
+	}
+}
+
+// actionRequest builds an approval page form POST with a valid CSRF token
+func actionRequest(server *Server, path string, form url.Values) *http.Request {
+	form.Set("csrf_token", server.signer.CSRFToken(path[strings.LastIndex(path, "/")+1:], ""))
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// recordingMover records moved articles instead of moving files
+type recordingMover struct {
+	mu      sync.Mutex
+	moved   []string
+	folders []string
+}
+
+func (m *recordingMover) MoveArticle(article *common.Article) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.moved = append(m.moved, article.GetCurrentStatus())
+	return nil
+}
+
+// Moved returns the statuses of the articles moved so far
+func (m *recordingMover) Moved() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.moved...)
+}
+
+func (m *recordingMover) GetAllMonitoredFolders() ([]string, error) {
+	return m.folders, nil
+}
+
+func TestReviseAction(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+	server := NewServer(cfg)
+	mover := &recordingMover{}
+	server.SetMover(mover)
+
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#REV001\"\ntitle: Revise me\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	rec := httptest.NewRecorder()
+	server.handleRevise(rec, httptest.NewRequest("GET", "/action/revise/REV001", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleRevise(rec, actionRequest(server, "/action/revise/REV001", url.Values{"comments": {""}}))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty comments, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleRevise(rec, actionRequest(server, "/action/revise/REV001", url.Values{"comments": {"Uddyb afsnit 2"}}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	entry, _ := server.flow.Get(article.ID)
+	if entry.State != StateRevisionRequested {
+		t.Errorf("Expected state RevisionRequested, got %s", entry.State)
+	}
+	if entry.Comments != "Uddyb afsnit 2" {
+		t.Errorf("Expected comments in flow entry, got %q", entry.Comments)
+	}
+
+	parsed, _ := common.ParseArticle(articlePath)
+	if parsed.GetCurrentStatus() != "revision" {
+		t.Errorf("Expected status revision, got %s", parsed.GetCurrentStatus())
+	}
+	if parsed.EditorComments != "Uddyb afsnit 2" {
+		t.Errorf("Expected editor_comments in frontmatter, got %q", parsed.EditorComments)
+	}
+
+	if len(mover.moved) != 1 || mover.moved[0] != "revision" {
+		t.Errorf("Expected one move with status revision, got %v", mover.moved)
+	}
+
+	// The author can resubmit - revision ends the approval cycle
+	if err := server.flow.Begin(parsed); err != nil {
+		t.Errorf("Expected resubmission to start a new cycle, got %v", err)
+	}
+}
+
+func TestScheduledApproval(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+
+	publishAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#SCH001\"\ntitle: Later\nauthor: TB\npublish_at: "+publishAt.Format(time.RFC3339)+"\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	// Approve + deploy of a scheduled article waits instead of deploying
+	complete, err := server.approve(article.ID, "tb", true)
+	if err != nil || !complete {
+		t.Fatalf("approve() = (%v, %v)", complete, err)
+	}
+
+	entry, _ := server.flow.Get(article.ID)
+	if entry.State != StateScheduled {
+		t.Fatalf("Expected Scheduled, got %s", entry.State)
+	}
+	if server.lastBuild != nil {
+		t.Error("Scheduled article triggered a build")
+	}
+
+	// A build before publish_at leaves it scheduled
+	server.markDeployed(time.Now())
+	if entry, _ := server.flow.Get(article.ID); entry.State != StateScheduled {
+		t.Errorf("Expected Scheduled after early build, got %s", entry.State)
+	}
+
+	// The first build after publish_at includes it
+	server.markDeployed(publishAt.Add(time.Minute))
+	if entry, _ := server.flow.Get(article.ID); entry.State != StateDeployedToMirror {
+		t.Errorf("Expected DeployedToMirror after publish_at, got %s", entry.State)
+	}
+}
+
+func TestUnpublish(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+	}
+	server := NewServer(cfg)
+
+	// Published before the publish flow existed - no entry yet
+	article := &common.Article{ID: "#UNP001", Title: "Take down", Author: "TB", FilePath: filepath.Join(tmpDir, "unp.md")}
+
+	// Without hugo the deploy fails, but the takedown is recorded and attempted right away
+	server.Unpublish(article)
+
+	entry, exists := server.flow.Get(article.ID)
+	if !exists || entry.State != StateUnpublished {
+		t.Fatalf("Expected Unpublished entry, got %+v", entry)
+	}
+	if note := entry.History[len(entry.History)-1].Note; note != "status unpublish" {
+		t.Errorf("Expected audit note, got %q", note)
+	}
+	if server.lastBuild == nil {
+		t.Fatal("Unpublish did not trigger an immediate build")
+	}
+
+	// Periodic folder scans see the status again - no second deploy
+	firstBuild := server.lastBuild
+	if err := server.Unpublish(article); err != nil {
+		t.Errorf("Repeated unpublish failed: %v", err)
+	}
+	if server.lastBuild != firstBuild {
+		t.Error("Repeated unpublish triggered another build")
+	}
+
+	// Republishing starts a new approval cycle
+	if err := server.flow.Begin(article); err != nil {
+		t.Errorf("Begin after unpublish failed: %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package approval
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset" // Decode non-UTF-8 replies (iso-8859-1, windows-1252)
+	"github.com/emersion/go-message/mail"
+
+	"norsetinge/src/config"
+)
+
+// defaultIMAPPollInterval is used when email.imap_poll_interval is not set
+const defaultIMAPPollInterval = 2 * time.Minute
+
+// replySubjectPattern finds the article ID in "Re: [Norsetinge #ABC123] Godkend: ..."
+var replySubjectPattern = regexp.MustCompile(`\[Norsetinge (#[A-Za-z0-9]{6})\]`)
+
+// replyTokenPattern finds the reply token in the Message-ID of an approval email,
+// "reply.{exp}.{sig}.{random}@domain" (see EmailSender.messageID)
+var replyTokenPattern = regexp.MustCompile(`^reply\.([0-9]+\.[0-9a-f]+)\.[0-9a-f]+@`)
+
+// quoteHeaderPattern matches the line mail clients put above a quoted reply
+var quoteHeaderPattern = regexp.MustCompile(`(?i)^(on .+ wrote:|den .+ skrev:|.+ skrev .+:|-----\s*original message\s*-----)$`)
+
+// ReplyAction is the editor's decision parsed from an email reply
+type ReplyAction string
+
+const (
+	ReplyApprove ReplyAction = "approve"
+	ReplyReject  ReplyAction = "reject"
+	ReplyRevise  ReplyAction = "revise"
+)
+
+// IMAPPoller reads replies to approval emails and applies them as approval actions
+type IMAPPoller struct {
+	cfg    *config.Config
+	server *Server
+}
+
+// NewIMAPPoller creates a new IMAP poller for the approval inbox
+func NewIMAPPoller(cfg *config.Config, server *Server) *IMAPPoller {
+	return &IMAPPoller{cfg: cfg, server: server}
+}
+
+// Enabled reports whether email approvals and the IMAP inbox are configured
+func (p *IMAPPoller) Enabled() bool {
+	return p.cfg.Email.Enabled && p.cfg.Email.IMAPHost != ""
+}
+
+// Start polls the inbox until stop is closed
+func (p *IMAPPoller) Start(stop <-chan struct{}) {
+	interval := p.cfg.Email.IMAPPollInterval
+	if interval <= 0 {
+		interval = defaultIMAPPollInterval
+	}
+
+	log.Printf("📬 Polling %s for approval replies every %v", p.cfg.Email.IMAPHost, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(); err != nil {
+			log.Printf("Warning: IMAP poll failed: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll handles all unseen messages in INBOX once. Every handled message is
+// marked \Seen so it is never applied twice.
+func (p *IMAPPoller) Poll() error {
+	c, err := p.connect()
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if _, err := c.Select("INBOX", false); err != nil {
+		return fmt.Errorf("failed to select INBOX: %w", err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return fmt.Errorf("failed to search INBOX: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, len(uids))
+	if err := c.UidFetch(seqSet, []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, section.FetchItem()}, messages); err != nil {
+		return fmt.Errorf("failed to fetch messages: %w", err)
+	}
+
+	handled := new(imap.SeqSet)
+	for msg := range messages {
+		if err := p.handleMessage(msg, section); err != nil {
+			log.Printf("Warning: Email reply not applied: %v", err)
+		}
+		handled.AddNum(msg.Uid)
+	}
+
+	if handled.Empty() {
+		return nil
+	}
+
+	flags := []interface{}{imap.SeenFlag}
+	if err := c.UidStore(handled, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil); err != nil {
+		return fmt.Errorf("failed to mark messages as seen: %w", err)
+	}
+	return nil
+}
+
+// connect dials the IMAP server (implicit TLS on 993, STARTTLS when offered otherwise) and logs in
+func (p *IMAPPoller) connect() (*client.Client, error) {
+	addr := net.JoinHostPort(p.cfg.Email.IMAPHost, fmt.Sprintf("%d", p.cfg.Email.IMAPPort))
+
+	var c *client.Client
+	var err error
+	if p.cfg.Email.IMAPPort == 993 {
+		c, err = client.DialTLS(addr, nil)
+	} else {
+		c, err = client.Dial(addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	}
+
+	if p.cfg.Email.IMAPPort != 993 {
+		if ok, _ := c.SupportStartTLS(); ok {
+			if err := c.StartTLS(nil); err != nil {
+				c.Logout()
+				return nil, fmt.Errorf("IMAP STARTTLS failed: %w", err)
+			}
+		}
+	}
+
+	if err := c.Login(p.cfg.Email.IMAPUser, p.cfg.Email.IMAPPassword); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("IMAP login failed: %w", err)
+	}
+
+	return c, nil
+}
+
+// handleMessage applies one reply if it comes from the approval recipient, refers to
+// an article pending approval and answers the approval email sent for that article and
+// editor. From and Subject are easily forged; the signed Message-ID it answers is not.
+func (p *IMAPPoller) handleMessage(msg *imap.Message, section *imap.BodySectionName) error {
+	if msg.Envelope == nil {
+		return errors.New("message has no envelope")
+	}
+
+	editor, ok := p.senderEditor(msg.Envelope)
+	if !ok {
+		return fmt.Errorf("ignoring %q: sender is not an approver", msg.Envelope.Subject)
+	}
+
+	match := replySubjectPattern.FindStringSubmatch(msg.Envelope.Subject)
+	if match == nil {
+		return fmt.Errorf("ignoring %q: no article ID in subject", msg.Envelope.Subject)
+	}
+	id := normalizeID(match[1])
+
+	body := msg.GetBody(section)
+	if body == nil {
+		return fmt.Errorf("message for %s has no body", id)
+	}
+
+	text, references, err := readReply(body)
+	if err != nil {
+		return fmt.Errorf("failed to read reply for %s: %w", id, err)
+	}
+
+	// Tokens are signed for the editor the email was sent to; the shared recipient has none
+	tokenEditor := ""
+	if len(p.cfg.Approval.Editors) > 0 {
+		tokenEditor = editor
+	}
+	if !p.answersApprovalEmail(id, tokenEditor, references) {
+		return fmt.Errorf("ignoring reply for %s from %s: it does not answer an approval email sent to them", id, editor)
+	}
+
+	action, comments := ParseReply(text)
+	if action == "" {
+		return fmt.Errorf("empty reply for %s", id)
+	}
+
+	log.Printf("📧 Email reply for %s from %s: %s", id, editor, action)
+
+	switch action {
+	case ReplyApprove:
+		_, err := p.server.approve(id, editor, false)
+		return err
+	case ReplyReject:
+		return p.server.reject(id, editor)
+	default:
+		return p.server.revise(id, editor, comments)
+	}
+}
+
+// senderEditor returns the editor behind the envelope sender: a configured editor's
+// email, or the approval recipient when no editors are configured
+func (p *IMAPPoller) senderEditor(envelope *imap.Envelope) (string, bool) {
+	for _, from := range envelope.From {
+		address := from.Address()
+
+		if len(p.cfg.Approval.Editors) > 0 {
+			if editor, found := p.server.knownEditor(address); found && strings.EqualFold(editor.Email, address) {
+				return editorID(editor), true
+			}
+			continue
+		}
+
+		if strings.EqualFold(address, p.cfg.Email.ApprovalRecipient) {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// answersApprovalEmail reports whether one of the referenced Message-IDs carries a
+// valid reply token for the article and editor
+func (p *IMAPPoller) answersApprovalEmail(id, editor string, references []string) bool {
+	for _, reference := range references {
+		match := replyTokenPattern.FindStringSubmatch(reference)
+		if match != nil && p.server.signer.VerifyReply(id, editor, match[1]) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseReply reads the editor's decision from the new (unquoted) part of a reply.
+// The first word decides: GODKEND/APPROVE or AFVIS/REJECT; any other text is
+// returned as revision comments. An empty reply returns an empty action.
+func ParseReply(text string) (ReplyAction, string) {
+	var lines []string
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, ">") || quoteHeaderPattern.MatchString(line) {
+			break // Quoted original message starts here
+		}
+		if line == "--" {
+			break // Signature
+		}
+		lines = append(lines, line)
+	}
+
+	reply := strings.TrimSpace(strings.Join(lines, "\n"))
+	if reply == "" {
+		return "", ""
+	}
+
+	firstWord := strings.ToUpper(strings.Trim(strings.Fields(reply)[0], ".,!:;"))
+	switch firstWord {
+	case "GODKEND", "GODKENDT", "APPROVE", "APPROVED":
+		return ReplyApprove, ""
+	case "AFVIS", "AFVIST", "REJECT", "REJECTED":
+		return ReplyReject, ""
+	default:
+		return ReplyRevise, reply
+	}
+}
+
+// readReply returns the first text/plain part of a message and the Message-IDs it
+// answers (In-Reply-To and References)
+func readReply(r io.Reader) (string, []string, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var references []string
+	for _, key := range []string{"In-Reply-To", "References"} {
+		ids, _ := mr.Header.MsgIDList(key)
+		references = append(references, ids...)
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return "", nil, errors.New("no text/plain part")
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		if header, ok := part.Header.(*mail.InlineHeader); ok {
+			contentType, _, _ := header.ContentType()
+			if contentType == "text/plain" || contentType == "" {
+				data, err := io.ReadAll(part.Body)
+				return string(data), references, err
+			}
+		}
+	}
+}
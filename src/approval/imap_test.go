@@ -0,0 +1,185 @@
+package approval
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		action   ReplyAction
+		comments string
+	}{
+		{"danish approve", "Godkend\n\nMvh Lars", ReplyApprove, ""},
+		{"english approve", "APPROVE.", ReplyApprove, ""},
+		{"reject", "afvis - ikke relevant", ReplyReject, ""},
+		{"comments", "Ret stavefejl i overskriften.\nUddyb afsnit 2.", ReplyRevise, "Ret stavefejl i overskriften.\nUddyb afsnit 2."},
+		{"quoted approve ignored", "Ser fin ud\n\nDen 3. okt. 2025 kl. 10.00 skrev Norsetinge:\n> Godkend", ReplyRevise, "Ser fin ud"},
+		{"gmail quote", "Reject\n\nOn Fri, Oct 3, 2025 at 10:00 Norsetinge wrote:\n> text", ReplyReject, ""},
+		{"signature only", "\n--\nSent from my iPhone", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, comments := ParseReply(tt.text)
+			if action != tt.action || comments != tt.comments {
+				t.Errorf("ParseReply() = (%q, %q), want (%q, %q)", action, comments, tt.action, tt.comments)
+			}
+		})
+	}
+}
+
+// startIMAPServer runs an in-memory IMAP server (user "username", password "password")
+func startIMAPServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Close() })
+
+	return listener.Addr().String()
+}
+
+// appendReply delivers a reply to the test inbox, answering the Message-ID inReplyTo (if set)
+func appendReply(t *testing.T, addr, from, subject, inReplyTo, body string) {
+	c, err := client.Dial(addr)
+	if err != nil {
+		t.Fatalf("Failed to dial IMAP: %v", err)
+	}
+	defer c.Logout()
+
+	if err := c.Login("username", "password"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	headers := ""
+	if inReplyTo != "" {
+		headers = "In-Reply-To: " + inReplyTo + "\r\nReferences: " + inReplyTo + "\r\n"
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: publisher@norsetinge.com\r\nSubject: %s\r\nDate: %s\r\n%sContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		from, subject, time.Now().Format(time.RFC1123Z), headers, body)
+	if err := c.Append("INBOX", nil, time.Now(), strings.NewReader(msg)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+}
+
+func TestIMAPPollerAppliesReplies(t *testing.T) {
+	tmpDir := t.TempDir()
+	addr := startIMAPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Email: config.EmailConfig{
+			Enabled:           true,
+			ApprovalRecipient: "editor@example.com",
+			IMAPHost:          host,
+			IMAPUser:          "username",
+			IMAPPassword:      "password",
+		},
+	}
+	fmt.Sscanf(port, "%d", &cfg.Email.IMAPPort)
+
+	srv := NewServer(cfg)
+
+	newPending := func(id string) string {
+		path := filepath.Join(tmpDir, strings.TrimPrefix(id, "#")+".md")
+		os.WriteFile(path, []byte("---\nid: \""+id+"\"\ntitle: Mail\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+		article, _ := common.ParseArticle(path)
+		srv.flow.Begin(article)
+		srv.flow.Transition(id, StatePendingApproval, "")
+		return path
+	}
+
+	rejectPath := newPending("#MAIL01")
+	revisePath := newPending("#MAIL02")
+	newPending("#MAIL03")
+	newPending("#MAIL04")
+	newPending("#MAIL05")
+
+	// The Message-ID of the approval email sent for an article
+	sent := func(id string) string {
+		return "<reply." + srv.signer.ReplyToken(id, "") + ".0123abcd@norsetinge.com>"
+	}
+
+	appendReply(t, addr, "Editor <editor@example.com>", "Re: [Norsetinge #MAIL01] Godkend: Mail", sent("#MAIL01"), "AFVIS\r\n\r\n> original")
+	appendReply(t, addr, "editor@example.com", "SV: [Norsetinge #mail02] Godkend: Mail", sent("#MAIL02"), "Kortere indledning, tak.\r\n")
+	appendReply(t, addr, "attacker@example.net", "Re: [Norsetinge #MAIL03] Godkend: Mail", sent("#MAIL03"), "GODKEND\r\n")
+	// Forged From and subject, but not a reply to any approval email
+	appendReply(t, addr, "editor@example.com", "Re: [Norsetinge #MAIL04] Godkend: Mail", "", "GODKEND\r\n")
+	// A genuine approval email's Message-ID reused for another article
+	appendReply(t, addr, "editor@example.com", "Re: [Norsetinge #MAIL05] Godkend: Mail", sent("#MAIL01"), "GODKEND\r\n")
+
+	poller := NewIMAPPoller(cfg, srv)
+	if !poller.Enabled() {
+		t.Fatal("Expected poller to be enabled")
+	}
+	if err := poller.Poll(); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	if entry, _ := srv.flow.Get("#MAIL01"); entry.State != StateRejected {
+		t.Errorf("MAIL01: expected Rejected, got %s", entry.State)
+	}
+	if article, _ := common.ParseArticle(rejectPath); article.GetCurrentStatus() != "rejected" {
+		t.Errorf("MAIL01: expected status rejected, got %s", article.GetCurrentStatus())
+	}
+
+	if entry, _ := srv.flow.Get("#MAIL02"); entry.State != StateRevisionRequested {
+		t.Errorf("MAIL02: expected RevisionRequested, got %s", entry.State)
+	}
+	if article, _ := common.ParseArticle(revisePath); article.EditorComments != "Kortere indledning, tak." {
+		t.Errorf("MAIL02: unexpected editor comments %q", article.EditorComments)
+	}
+
+	// Replies from anyone but the approval recipient, and replies that do not answer
+	// the approval email for the article, are ignored
+	for _, id := range []string{"#MAIL03", "#MAIL04", "#MAIL05"} {
+		if entry, _ := srv.flow.Get(id); entry.State != StatePendingApproval {
+			t.Errorf("%s: expected PendingApproval, got %s", id, entry.State)
+		}
+	}
+
+	// All handled messages are marked seen so the next poll does nothing
+	c, err := client.Dial(addr)
+	if err != nil {
+		t.Fatalf("Failed to dial IMAP: %v", err)
+	}
+	defer c.Logout()
+	c.Login("username", "password")
+	c.Select("INBOX", true)
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	unseen, err := c.Search(criteria)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(unseen) != 0 {
+		t.Errorf("Expected no unseen messages, got %d", len(unseen))
+	}
+}
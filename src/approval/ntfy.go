@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"norsetinge/src/config"
 )
@@ -38,19 +39,13 @@ func NewNtfySender(cfg *config.Config) *NtfySender {
 	return &NtfySender{cfg: cfg}
 }
 
-// SendApprovalNotification sends a simple notification with approval URL
-func (n *NtfySender) SendApprovalNotification(title, author, previewURLPath, articleID string) error {
+// SendApprovalNotification sends a simple notification with the signed approval URL
+func (n *NtfySender) SendApprovalNotification(title, author, approvalURL string) error {
 	if !n.cfg.Ntfy.Enabled {
 		log.Printf("ntfy notifications disabled")
 		return nil
 	}
 
-	// Generate Tailscale approval URL (https via tailscale serve)
-	// This shows the approval page with 3 buttons + article preview
-	approvalURL := fmt.Sprintf("https://%s/approve/%s",
-		n.cfg.Approval.TailscaleHostname,
-		articleID)
-
 	msg := NtfyMessage{
 		Topic:    n.cfg.Ntfy.Topic,
 		Title:    fmt.Sprintf("📰 %s", title),
@@ -69,6 +64,24 @@ func (n *NtfySender) SendApprovalNotification(title, author, previewURLPath, art
 	return n.send(msg)
 }
 
+// SendRevisionNotification posts a revision request to the editors' topic.
+// The author is emailed separately (SendRevisionEmail).
+func (n *NtfySender) SendRevisionNotification(title, author, comments string) error {
+	if !n.cfg.Ntfy.Enabled {
+		return nil
+	}
+
+	msg := NtfyMessage{
+		Topic:    n.cfg.Ntfy.Topic,
+		Title:    fmt.Sprintf("✏️ Rettelser: %s", title),
+		Message:  fmt.Sprintf("Til: %s\n\n%s\n\nArtiklen er flyttet til afventer-rettelser/", author, comments),
+		Priority: 4,
+		Tags:     []string{"pencil2"},
+	}
+
+	return n.send(msg)
+}
+
 // send sends a ntfy notification using headers (not JSON body)
 func (n *NtfySender) send(msg NtfyMessage) error {
 	url := fmt.Sprintf("%s/%s", n.cfg.Ntfy.Server, n.cfg.Ntfy.Topic)
@@ -82,7 +95,7 @@ func (n *NtfySender) send(msg NtfyMessage) error {
 	// Set headers according to ntfy documentation
 	req.Header.Set("Title", msg.Title)
 	req.Header.Set("Priority", fmt.Sprintf("%d", msg.Priority))
-	req.Header.Set("Tags", "newspaper")
+	req.Header.Set("Tags", strings.Join(msg.Tags, ","))
 
 	// Add action button as JSON in header
 	if len(msg.Actions) > 0 {
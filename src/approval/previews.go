@@ -0,0 +1,136 @@
+package approval
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+const (
+	// defaultPreviewWorkers is the number of concurrent preview builds when hugo.preview_workers is not set
+	defaultPreviewWorkers = 2
+
+	// previewJobRetention is how long finished jobs stay visible
+	previewJobRetention = time.Hour
+)
+
+// PreviewJobState is the state of a queued preview build
+type PreviewJobState string
+
+const (
+	PreviewQueued   PreviewJobState = "queued"
+	PreviewBuilding PreviewJobState = "building"
+	PreviewDone     PreviewJobState = "done"
+	PreviewFailed   PreviewJobState = "failed"
+)
+
+// PreviewJob is the status of one article's preview build
+type PreviewJob struct {
+	ID       string          `json:"id"`
+	Title    string          `json:"title"`
+	State    PreviewJobState `json:"state"`
+	Queued   time.Time       `json:"queued"`
+	Started  time.Time       `json:"started,omitempty"`
+	Finished time.Time       `json:"finished,omitempty"`
+	Err      string          `json:"error,omitempty"`
+}
+
+// PreviewPool builds previews in the background with at most hugo.preview_workers at a time.
+// Each build has its own workspace, so concurrent builds do not interfere.
+type PreviewPool struct {
+	build   func(article *common.Article) error
+	slots   chan struct{}
+	running sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*PreviewJob
+}
+
+// NewPreviewPool creates a pool that runs build for each queued article
+func NewPreviewPool(cfg *config.Config, build func(article *common.Article) error) *PreviewPool {
+	workers := cfg.Hugo.PreviewWorkers
+	if workers <= 0 {
+		workers = defaultPreviewWorkers
+	}
+
+	return &PreviewPool{
+		build: build,
+		slots: make(chan struct{}, workers),
+		jobs:  make(map[string]*PreviewJob),
+	}
+}
+
+// Enqueue queues a preview build. Returns false if the article is already queued or building.
+func (p *PreviewPool) Enqueue(article *common.Article) bool {
+	p.mu.Lock()
+	if job, exists := p.jobs[article.ID]; exists && (job.State == PreviewQueued || job.State == PreviewBuilding) {
+		p.mu.Unlock()
+		return false
+	}
+	job := &PreviewJob{ID: article.ID, Title: article.Title, State: PreviewQueued, Queued: time.Now()}
+	p.jobs[article.ID] = job
+	p.mu.Unlock()
+
+	p.running.Add(1)
+	go func() {
+		defer p.running.Done()
+
+		p.slots <- struct{}{} // Wait for a free worker
+		defer func() { <-p.slots }()
+
+		p.update(job, func() {
+			job.State = PreviewBuilding
+			job.Started = time.Now()
+		})
+
+		err := p.build(article)
+
+		p.update(job, func() {
+			job.Finished = time.Now()
+			job.State = PreviewDone
+			if err != nil {
+				job.State = PreviewFailed
+				job.Err = err.Error()
+			}
+		})
+		if err != nil {
+			log.Printf("Preview build failed for %s: %v", article.Title, err)
+		}
+	}()
+
+	return true
+}
+
+// Wait blocks until all queued builds have finished
+func (p *PreviewPool) Wait() {
+	p.running.Wait()
+}
+
+// Jobs returns the current and recently finished jobs, oldest first
+func (p *PreviewPool) Jobs() []PreviewJob {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var jobs []PreviewJob
+	for id, job := range p.jobs {
+		if !job.Finished.IsZero() && time.Since(job.Finished) > previewJobRetention {
+			delete(p.jobs, id)
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Queued.Before(jobs[j].Queued) })
+	return jobs
+}
+
+// update changes a job under the lock
+func (p *PreviewPool) update(job *PreviewJob, change func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	change()
+}
@@ -0,0 +1,76 @@
+package approval
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestPreviewPoolBoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	built := make(map[string]bool)
+
+	pool := NewPreviewPool(&config.Config{Hugo: config.HugoConfig{PreviewWorkers: 3}}, func(article *common.Article) error {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+
+		mu.Lock()
+		built[article.ID] = true
+		mu.Unlock()
+
+		if article.ID == "#PRV009" {
+			return fmt.Errorf("hugo failed")
+		}
+		return nil
+	})
+
+	// Ten articles dropped into udgiv/ at once
+	for i := 0; i < 10; i++ {
+		pool.Enqueue(&common.Article{ID: fmt.Sprintf("#PRV%03d", i), Title: "Preview"})
+	}
+	if pool.Enqueue(&common.Article{ID: "#PRV000"}) {
+		t.Error("Article already queued was queued again")
+	}
+
+	pool.Wait()
+
+	if len(built) != 10 {
+		t.Errorf("Expected 10 previews built, got %d", len(built))
+	}
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most 3 concurrent builds, got %d", peak.Load())
+	}
+
+	jobs := pool.Jobs()
+	if len(jobs) != 10 {
+		t.Fatalf("Expected 10 jobs, got %d", len(jobs))
+	}
+	for _, job := range jobs {
+		want := PreviewDone
+		if job.ID == "#PRV009" {
+			want = PreviewFailed
+		}
+		if job.State != want || job.Finished.IsZero() {
+			t.Errorf("Job %s: expected %s, got %+v", job.ID, want, job)
+		}
+	}
+
+	// A finished article can be queued again (e.g. an update)
+	if !pool.Enqueue(&common.Article{ID: "#PRV000"}) {
+		t.Error("Finished article could not be queued again")
+	}
+	pool.Wait()
+}
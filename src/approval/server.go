@@ -4,65 +4,139 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"norsetinge/src/builder"
 	"norsetinge/src/common"
 	"norsetinge/src/config"
 	"norsetinge/src/deployer"
+	"norsetinge/src/translator"
 )
 
+// previewIDPattern matches the article ID part of a /preview/ URL
+var previewIDPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
 // Server handles approval web requests
 type Server struct {
-	cfg             *config.Config
-	ntfySender      *NtfySender
-	hugoBuilder     *builder.HugoBuilder
-	deployer        *deployer.Deployer
-	pendingArticles map[string]*PendingArticle
-	mu              sync.RWMutex
-	mover           FileMover
+	cfg         *config.Config
+	ntfySender  *NtfySender
+	emailSender *EmailSender
+	hugoBuilder *builder.HugoBuilder
+	deployer    *deployer.Deployer
+	translator  *translator.Translator
+	flow        *PublishFlow
+	mover       FileMover
+	signer      *linkSigner
+	versions    *VersionStore
+	scheduler   *builder.Scheduler
+	previews    *PreviewPool
+
+	runMu          sync.Mutex
+	lastBuild      *runResult
+	lastDeploy     *runResult
+	lastPlan       *deployer.DeployReport // Last dry-run
+	pendingDeletes *deployer.DeployReport // Deploy stopped by deploy.max_deletes, awaiting confirmation
 }
 
 // FileMover interface for moving files based on status
 type FileMover interface {
 	MoveArticle(article *common.Article) error
+	GetAllMonitoredFolders() ([]string, error)
+}
+
+// runResult is the outcome of the last site build or deploy
+type runResult struct {
+	Finished time.Time
+	Duration time.Duration
+	Err      string
+	Changes  *builder.BuildResult   // Content changes, for builds
+	Rollback *deployer.DeployCommit // Deploy commit that was restored, for rollbacks
 }
 
-// PendingArticle represents an article awaiting approval
+// PendingArticle is an article's entry in the publish flow
 type PendingArticle struct {
-	ID               string              `json:"id"`
-	Article          *common.Article     `json:"article"`
-	PreviewPath      string              `json:"preview_path"` // Hugo preview HTML path (for iframe)
-	Approved         bool                `json:"approved"`
-	Rejected         bool                `json:"rejected"`
-	Comments         string              `json:"comments"`
-	NotificationSent bool                `json:"notification_sent"` // To prevent re-sending notifications
+	ID               string          `json:"id"`
+	Article          *common.Article `json:"article"`
+	PreviewPath      string          `json:"preview_path"` // Hugo preview HTML path (for iframe)
+	Comments         string          `json:"comments"`
+	NotificationSent bool            `json:"notification_sent"` // To prevent re-sending notifications
+	TranslationsDone bool            `json:"translations_done"`
+	State            ApprovalState   `json:"state"`
+	History          []StateChange   `json:"history"`
+
+	RequiredApprovals int        `json:"required_approvals,omitempty"` // From approval.policies when requested
+	Approvals         []Approval `json:"approvals,omitempty"`          // Approvals in the current cycle
+}
+
+// URLID returns the article ID without '#', which would otherwise start a URL fragment
+func (p *PendingArticle) URLID() string {
+	return strings.TrimPrefix(p.ID, "#")
+}
+
+// approvalPage is the template data for the approval page
+type approvalPage struct {
+	*PendingArticle
+	Editor    string
+	CSRFToken string
+	Diff      *articleDiff // Changes since the last approved version, for updates
 }
 
-// NewServer creates a new approval server
+// NewServer creates a new approval server. Call Resume once the mover is set.
 func NewServer(cfg *config.Config) *Server {
 	s := &Server{
-		cfg:             cfg,
-		ntfySender:      NewNtfySender(cfg),
-		hugoBuilder:     builder.NewHugoBuilder(cfg),
-		deployer:        deployer.NewDeployer(cfg),
-		pendingArticles: make(map[string]*PendingArticle),
+		cfg:         cfg,
+		ntfySender:  NewNtfySender(cfg),
+		emailSender: NewEmailSender(cfg),
+		hugoBuilder: builder.NewHugoBuilder(cfg),
+		deployer:    deployer.NewDeployer(cfg),
+		translator:  translator.NewTranslator(cfg),
+		signer:      newLinkSigner(cfg),
 	}
+	s.versions = NewVersionStore(s.getVersionsPath())
+	s.scheduler = builder.NewScheduler(cfg, s.buildAndDeploy)
+	s.previews = NewPreviewPool(cfg, s.buildAndNotify)
 
-	// Load pending articles from disk
-	if err := s.loadPendingArticles(); err != nil {
-		log.Printf("Warning: Failed to load pending articles: %v", err)
+	// Load publish flow journal from disk
+	flow, err := NewPublishFlow(s.getPublishFlowPath())
+	if err != nil {
+		log.Printf("Warning: Failed to load publish flow: %v", err)
+	}
+	s.flow = flow
+
+	if err := s.migratePendingArticles(); err != nil {
+		log.Printf("Warning: Failed to migrate pending articles: %v", err)
 	}
 
 	return s
 }
 
+// ApprovalURL returns the signed approval page URL (https via tailscale serve)
+func (s *Server) ApprovalURL(id string) string {
+	return s.EditorApprovalURL(id, "")
+}
+
+// EditorApprovalURL returns a signed approval page URL that identifies the editor
+func (s *Server) EditorApprovalURL(id, editor string) string {
+	return "https://" + s.cfg.Approval.TailscaleHostname + s.approvalPath(id, editor)
+}
+
+// approvalPath returns the signed approval page path, valid for approval.link_ttl.
+// ID without '#' - it would otherwise start a URL fragment.
+func (s *Server) approvalPath(id, editor string) string {
+	return "/approve/" + url.PathEscape(strings.TrimPrefix(normalizeID(id), "#")) + "?" + s.signer.SignedQuery(id, editor)
+}
+
 // SetMover sets the file mover for handling file movements
 func (s *Server) SetMover(mover FileMover) {
 	s.mover = mover
@@ -70,151 +144,262 @@ func (s *Server) SetMover(mover FileMover) {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	// Serve Hugo public directory for previews
-	http.Handle("/preview/", http.StripPrefix("/preview/", http.FileServer(http.Dir(s.cfg.Hugo.PublicDir))))
-
-	http.HandleFunc("/approve/", s.handleApproval)
-	http.HandleFunc("/action/approve/", s.handleApprove)
-	http.HandleFunc("/action/approve-deploy/", s.handleApproveAndDeploy)
-	http.HandleFunc("/action/reject/", s.handleReject)
-
 	addr := fmt.Sprintf("%s:%d", s.cfg.Approval.Host, s.cfg.Approval.Port)
 	log.Printf("Approval server starting on %s", addr)
 
-	return http.ListenAndServe(addr, nil)
+	return http.ListenAndServe(addr, s.routes())
 }
 
-// RequestApproval creates approval request and sends notification if not already sent.
-// FIXED: Moved long operations (BuildPreview, SendNotification) outside mutex to prevent deadlock.
-func (s *Server) RequestApproval(article *common.Article) error {
-	id := article.ID
+// routes registers all HTTP handlers
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
 
-	// Phase 1: Check if already exists (short lock)
-	s.mu.Lock()
-	if pending, exists := s.pendingArticles[id]; exists && pending.NotificationSent {
-		s.mu.Unlock()
-		log.Printf("⏭️ Skipping notification, already pending for: %s", article.Title)
-		return nil
+	// Serve previews from their workspaces
+	mux.HandleFunc("/preview/", s.handlePreview)
+
+	mux.HandleFunc("/dashboard", s.handleDashboard)
+	mux.HandleFunc("/approve/", s.handleApproval)
+	mux.HandleFunc("/action/approve/", s.handleApprove)
+	mux.HandleFunc("/action/approve-deploy/", s.handleApproveAndDeploy)
+	mux.HandleFunc("/action/reject/", s.handleReject)
+	mux.HandleFunc("/action/revise/", s.handleRevise)
+
+	s.apiRoutes(mux)
+
+	return mux
+}
+
+// RequestApproval starts the publish flow for an article unless it is already in flight.
+// The flow state is persisted before the long operations (preview build, notification),
+// which run in the preview pool, so a crash can be resumed and the caller never waits for hugo.
+func (s *Server) RequestApproval(article *common.Article) error {
+	if err := s.flow.Begin(article); err != nil {
+		if errors.Is(err, errInFlight) {
+			log.Printf("⏭️ Skipping notification, already in flow: %s (%v)", article.Title, err)
+			return nil
+		}
+		return fmt.Errorf("failed to start publish flow: %w", err)
 	}
 
-	// Reserve this ID with placeholder to prevent duplicate processing
-	s.pendingArticles[id] = &PendingArticle{
-		ID:               id,
-		Article:          article,
-		PreviewPath:      "",
-		NotificationSent: false, // Not sent yet
+	required := s.requiredApprovals(article)
+	if err := s.flow.Update(article.ID, func(p *PendingArticle) { p.RequiredApprovals = required }); err != nil {
+		log.Printf("Warning: Failed to save publish flow: %v", err)
 	}
-	s.mu.Unlock()
 
-	// Phase 2: Build preview (NO LOCK - can take seconds)
+	s.previews.Enqueue(article)
+	return nil
+}
+
+// WaitForPreviews blocks until all queued preview builds and notifications are done
+func (s *Server) WaitForPreviews() {
+	s.previews.Wait()
+}
+
+// PreviewJobs returns the status of current and recent preview builds
+func (s *Server) PreviewJobs() []PreviewJob {
+	return s.previews.Jobs()
+}
+
+// buildAndNotify builds the preview and sends the approval notification (state PreviewBuilding).
+// On failure the article goes back to IDGenerated so the next folder scan retries it.
+func (s *Server) buildAndNotify(article *common.Article) error {
+	id := article.ID
+
 	log.Printf("Building Hugo preview for: %s", article.Title)
 	htmlPath, err := s.hugoBuilder.BuildPreview(article)
 	if err != nil {
-		// Remove placeholder on error
-		s.mu.Lock()
-		delete(s.pendingArticles, id)
-		s.mu.Unlock()
+		s.transition(id, StateIDGenerated, fmt.Sprintf("preview build failed: %v", err))
 		return fmt.Errorf("failed to build Hugo preview: %w", err)
 	}
 
-	// Phase 3: Send notification (NO LOCK - HTTP request)
+	if err := s.notifyApprovers(article, htmlPath); err != nil {
+		s.transition(id, StateIDGenerated, fmt.Sprintf("notification failed: %v", err))
+		return err
+	}
+
+	if err := s.flow.Update(id, func(p *PendingArticle) {
+		p.Article = article.Clone()
+		p.PreviewPath = htmlPath
+		p.NotificationSent = true
+	}); err != nil {
+		log.Printf("Warning: Failed to save publish flow: %v", err)
+	}
+	s.transition(id, StatePendingApproval, "")
+
+	log.Printf("Approval request sent for: %s (ID: %s, Preview: %s)", article.Title, id, htmlPath)
+	return nil
+}
+
+// notifyApprovers sends the approval request on every enabled channel (ntfy, email).
+// Fails only if all enabled channels fail.
+func (s *Server) notifyApprovers(article *common.Article, htmlPath string) error {
+	var errs []error
+	sent := 0
+
 	if s.cfg.Ntfy.Enabled {
-		if err := s.ntfySender.SendApprovalNotification(
-			article.Title,
-			article.Author,
-			htmlPath,
-			id,
-		); err != nil {
+		if err := s.ntfySender.SendApprovalNotification(article.Title, article.Author, s.ApprovalURL(article.ID)); err != nil {
 			log.Printf("Warning: Failed to send ntfy notification: %v", err)
-			// Remove placeholder on error so it can be retried
-			s.mu.Lock()
-			delete(s.pendingArticles, id)
-			s.mu.Unlock()
-			return err
+			errs = append(errs, err)
+		} else {
+			sent++
 		}
 	}
 
-	// Phase 4: Update with final data (short lock)
-	s.mu.Lock()
-	s.pendingArticles[id] = &PendingArticle{
-		ID:               id,
-		Article:          article,
-		PreviewPath:      htmlPath,
-		NotificationSent: true, // Mark as sent
+	if s.cfg.Email.Enabled {
+		for _, recipient := range s.emailRecipients() {
+			url := s.EditorApprovalURL(article.ID, recipient.editor)
+			token := s.signer.ReplyToken(article.ID, recipient.editor)
+			if err := s.emailSender.SendApprovalEmail(article, recipient.address, url, token); err != nil {
+				log.Printf("Warning: Failed to send approval email to %s: %v", recipient.address, err)
+				errs = append(errs, err)
+			} else {
+				sent++
+			}
+		}
 	}
 
-	// Persist to disk (we already hold lock, use NoLock version)
-	if err := s.savePendingArticlesNoLock(); err != nil {
-		log.Printf("Warning: Failed to save pending articles: %v", err)
+	if sent == 0 && len(errs) > 0 {
+		return errors.Join(errs...)
 	}
-	s.mu.Unlock()
-
-	log.Printf("Approval request sent for: %s (ID: %s, Preview: %s)", article.Title, id, htmlPath)
 	return nil
 }
 
-// handleApproval shows the approval page
+// emailRecipient is an approval email address and the editor its link identifies
+type emailRecipient struct {
+	address string
+	editor  string
+}
+
+// emailRecipients returns every editor with an email address, each getting a personal
+// link, or the shared approval_recipient if no editor has one
+func (s *Server) emailRecipients() []emailRecipient {
+	var recipients []emailRecipient
+	for _, editor := range s.cfg.Approval.Editors {
+		if editor.Email != "" {
+			recipients = append(recipients, emailRecipient{address: editor.Email, editor: editorID(editor)})
+		}
+	}
+
+	if len(recipients) == 0 {
+		recipients = append(recipients, emailRecipient{address: s.cfg.Email.ApprovalRecipient})
+	}
+	return recipients
+}
+
+// handleApproval shows the approval page. The link must carry a valid, unexpired signature.
 func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/approve/"):]
+	id := normalizeID(r.URL.Path[len("/approve/"):])
 
-	s.mu.RLock()
-	pending, exists := s.pendingArticles[id]
-	s.mu.RUnlock()
+	if err := s.signer.VerifyLink(id, r.URL.Query()); err != nil {
+		log.Printf("Rejected approval link for %s: %v", id, err)
+		http.Error(w, "Approval link is invalid or expired", http.StatusForbidden)
+		return
+	}
 
+	editor, ok := s.authorizeEditor(w, r, r.URL.Query().Get("editor"))
+	if !ok {
+		return
+	}
+
+	pending, exists := s.flow.Get(id)
 	if !exists {
 		http.Error(w, "Article not found", http.StatusNotFound)
 		return
 	}
 
-	tmpl := template.Must(template.New("approval").Parse(approvalTemplate))
-	tmpl.Execute(w, pending)
+	if pending.State != StatePendingApproval {
+		http.Error(w, fmt.Sprintf("Article is no longer pending approval (state: %s)", pending.State), http.StatusConflict)
+		return
+	}
+
+	tmpl := template.Must(template.New("approval").Funcs(template.FuncMap{
+		"formatTime": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	}).Parse(approvalTemplate))
+	tmpl.Execute(w, approvalPage{
+		PendingArticle: pending,
+		Editor:         editor,
+		CSRFToken:      s.signer.CSRFToken(id, editor),
+		Diff:           s.updateDiff(pending.Article),
+	})
 }
 
-// handleApprove handles normal approval (no immediate deploy)
-func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/action/approve/"):]
+// updateDiff compares an article with its last approved version, or returns nil for new articles
+func (s *Server) updateDiff(article *common.Article) *articleDiff {
+	version, previous, err := s.versions.Latest(article.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to load previous version of %s: %v", article.ID, err)
+		return nil
+	}
+	if version == nil {
+		return nil
+	}
 
-	s.mu.Lock()
-	pending, exists := s.pendingArticles[id]
-	if !exists {
-		s.mu.Unlock()
-		http.Error(w, "Article not found", http.StatusNotFound)
+	diff, err := diffArticles(previous, article, version.Approved)
+	if err != nil {
+		log.Printf("Warning: Failed to diff %s: %v", article.ID, err)
+		return nil
+	}
+	return diff
+}
+
+// handlePreview serves a preview from its workspace: /preview/{id}/... is
+// previews/{id}/public/... - the production public directory is not served
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	idSlug, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/preview/"), "/")
+	if !previewIDPattern.MatchString(idSlug) {
+		http.NotFound(w, r)
 		return
 	}
-	delete(s.pendingArticles, id)
 
-	// Persist the change to the pending articles list on disk (we hold lock, use NoLock version)
-	if err := s.savePendingArticlesNoLock(); err != nil {
-		log.Printf("Warning: Failed to save pending articles list: %v", err)
+	files := http.FileServer(http.Dir(s.hugoBuilder.PreviewPublicDir(idSlug)))
+	http.StripPrefix("/preview/"+idSlug, files).ServeHTTP(w, r)
+}
+
+// actionID checks that a workflow action is a POST with a valid CSRF token from the
+// approval page and returns the article ID and the acting editor. Writes the error response if not.
+func (s *Server) actionID(w http.ResponseWriter, r *http.Request, prefix string) (string, string, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", "", false
 	}
-	s.mu.Unlock()
 
-	log.Printf("Article approved: %s - moving to udgivet/", pending.Article.Title)
+	id := normalizeID(strings.TrimPrefix(r.URL.Path, prefix))
+	formEditor := r.FormValue("editor")
 
-	// Move article to udgivet/
-	pending.Article.UpdateStatus("published")
-	if err := pending.Article.WriteFrontmatter(); err != nil {
-		log.Printf("Error updating article status: %v", err)
-		http.Error(w, "Failed to update article", http.StatusInternalServerError)
-		return
+	if err := s.signer.VerifyCSRF(id, formEditor, r.FormValue("csrf_token")); err != nil {
+		log.Printf("Rejected action on %s: CSRF token %v", id, err)
+		http.Error(w, "Invalid or expired form - reload the approval page", http.StatusForbidden)
+		return "", "", false
 	}
 
-	if s.mover != nil {
-		if err := s.mover.MoveArticle(pending.Article); err != nil {
-			log.Printf("Error moving article: %v", err)
-		} else {
-			log.Printf("✓ Article moved to udgivet/: %s", pending.Article.Title)
-		}
+	editor, ok := s.authorizeEditor(w, r, formEditor)
+	if !ok {
+		return "", "", false
 	}
 
-	// Clean up preview files
-	s.cleanupPreviewFiles(pending.Article)
+	return id, editor, true
+}
 
-	// Clear ntfy notification for this approval
-	if s.cfg.Ntfy.Enabled {
-		if err := s.ntfySender.ClearAllNotifications(); err != nil {
-			log.Printf("Warning: Failed to clear ntfy notifications: %v", err)
-		}
+// handleApprove handles normal approval (no immediate deploy)
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	id, editor, ok := s.actionID(w, r, "/action/approve/")
+	if !ok {
+		return
+	}
+
+	complete, err := s.approve(id, editor, false)
+	if err != nil {
+		s.writeActionError(w, err)
+		return
+	}
+	if !complete {
+		s.writeAwaitingApprovals(w, id)
+		return
+	}
+	if entry, _ := s.flow.Get(id); entry.State == StateScheduled {
+		s.writeScheduled(w, entry)
+		return
 	}
 
 	fmt.Fprintf(w, `
@@ -222,46 +407,174 @@ func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
 		<html><head><meta charset="UTF-8"><title>Godkendt</title></head>
 		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
 			<h1>✅ Artikel Godkendt!</h1>
-			<p>Artiklen er flyttet til udgivet/ og vil blive deployeret ved næste automatiske build (hver 10-15 min).</p>
+			<p>Artiklen er flyttet til udgivet/. Sitet bygges og deployeres automatisk om et øjeblik.</p>
 		</body></html>
 	`)
 }
 
 // handleApproveAndDeploy handles immediate approval + deploy
 func (s *Server) handleApproveAndDeploy(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/action/approve-deploy/"):]
+	id, editor, ok := s.actionID(w, r, "/action/approve-deploy/")
+	if !ok {
+		return
+	}
 
-	s.mu.Lock()
-	pending, exists := s.pendingArticles[id]
-	if !exists {
-		s.mu.Unlock()
-		http.Error(w, "Article not found", http.StatusNotFound)
+	complete, err := s.approve(id, editor, true)
+	if err != nil {
+		s.writeActionError(w, err)
+		return
+	}
+	if !complete {
+		s.writeAwaitingApprovals(w, id)
+		return
+	}
+	if entry, _ := s.flow.Get(id); entry.State == StateScheduled {
+		s.writeScheduled(w, entry)
 		return
 	}
-	delete(s.pendingArticles, id)
-	s.mu.Unlock()
 
-	// Persist the change to the pending articles list on disk
-	if err := s.savePendingArticles(); err != nil {
-		log.Printf("Warning: Failed to save pending articles list: %v", err)
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html><head><meta charset="UTF-8"><title>Deployeret</title></head>
+		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
+			<h1>⚡ Artikel Godkendt & Deployeret!</h1>
+			<p>Artiklen er nu live på norsetinge.com</p>
+			<p style="color: #666; font-size: 14px;">Bygget, deployeret og arkiveret i udgivet/</p>
+		</body></html>
+	`)
+}
+
+// handleReject handles rejection action
+func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
+	id, editor, ok := s.actionID(w, r, "/action/reject/")
+	if !ok {
+		return
 	}
 
-	log.Printf("Article approved with immediate deploy: %s", pending.Article.Title)
+	if err := s.reject(id, editor); err != nil {
+		s.writeActionError(w, err)
+		return
+	}
 
-	// 1. Move article to udgivet/
-	pending.Article.UpdateStatus("published")
-	if err := pending.Article.WriteFrontmatter(); err != nil {
-		log.Printf("Error updating article status: %v", err)
-		http.Error(w, "Failed to update article", http.StatusInternalServerError)
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html><head><meta charset="UTF-8"><title>Afvist</title></head>
+		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
+			<h1>❌ Artikel Afvist</h1>
+			<p>Artiklen er flyttet til afvist/ mappen.</p>
+		</body></html>
+	`)
+}
+
+// handleRevise handles "suggest changes": the editor's comments are stored in the
+// article and it is sent back to the author via afventer-rettelser/
+func (s *Server) handleRevise(w http.ResponseWriter, r *http.Request) {
+	id, editor, ok := s.actionID(w, r, "/action/revise/")
+	if !ok {
 		return
 	}
 
-	if s.mover != nil {
-		if err := s.mover.MoveArticle(pending.Article); err != nil {
-			log.Printf("Error moving article: %v", err)
-		} else {
-			log.Printf("✓ Article moved to udgivet/: %s", pending.Article.Title)
+	comments := strings.TrimSpace(r.FormValue("comments"))
+	if comments == "" {
+		http.Error(w, "Comments are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.revise(id, editor, comments); err != nil {
+		s.writeActionError(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html><head><meta charset="UTF-8"><title>Rettelser sendt</title></head>
+		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
+			<h1>✏️ Rettelser Sendt</h1>
+			<p>Artiklen er flyttet til afventer-rettelser/ og forfatteren har fået besked.</p>
+		</body></html>
+	`)
+}
+
+// writeAwaitingApprovals confirms an approval that is not yet enough to publish
+func (s *Server) writeAwaitingApprovals(w http.ResponseWriter, id string) {
+	pending, _ := s.flow.Get(id)
+
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html><head><meta charset="UTF-8"><title>Godkendelse registreret</title></head>
+		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
+			<h1>👍 Godkendelse Registreret</h1>
+			<p>%d af %d godkendelser. Artiklen udgives når de resterende redaktører har godkendt.</p>
+		</body></html>
+	`, len(pending.Approvals), pending.Required())
+}
+
+// writeScheduled confirms an approval that waits for publish_at
+func (s *Server) writeScheduled(w http.ResponseWriter, entry *PendingArticle) {
+	fmt.Fprintf(w, `
+		<!DOCTYPE html>
+		<html><head><meta charset="UTF-8"><title>Planlagt</title></head>
+		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
+			<h1>⏰ Artikel Godkendt og Planlagt</h1>
+			<p>Artiklen er flyttet til udgivet/ og udgives ved første automatiske build efter %s.</p>
+		</body></html>
+	`, entry.Article.PublishAt.Format("2006-01-02 15:04"))
+}
+
+// writeActionError maps flow errors to HTTP responses
+func (s *Server) writeActionError(w http.ResponseWriter, err error) {
+	switch status := actionErrorStatus(err); status {
+	case http.StatusNotFound:
+		http.Error(w, "Article not found", status)
+	case http.StatusConflict:
+		if errors.Is(err, errAlreadyApproved) {
+			http.Error(w, "You have already approved this article", status)
+			return
 		}
+		http.Error(w, "Article is no longer pending approval", status)
+	default:
+		log.Printf("Error handling approval action: %v", err)
+		http.Error(w, err.Error(), status)
+	}
+}
+
+// actionErrorStatus returns the HTTP status code for a flow error
+func actionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidTransition), errors.Is(err, errAlreadyApproved):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// approve records the editor's approval. Once the article has all required approvals it
+// is moved to udgivet/ and translated; complete reports whether that happened.
+// With deployNow the site is translated, built and deployed before returning.
+func (s *Server) approve(id, editor string, deployNow bool) (complete bool, err error) {
+	// The approval is the atomic claim - a second click gets errInvalidTransition
+	complete, err = s.flow.Approve(id, editor)
+	if err != nil {
+		return false, err
+	}
+
+	pending, _ := s.flow.Get(id)
+	if !complete {
+		log.Printf("Article approved by %s: %s - %d of %d approvals", editorName(editor), pending.Article.Title, len(pending.Approvals), pending.Required())
+		return false, nil
+	}
+
+	log.Printf("Article approved by %s: %s - moving to udgivet/ (deploy now: %v)", editorName(editor), pending.Article.Title, deployNow)
+
+	if err := s.publishApproved(pending.Article); err != nil {
+		return true, fmt.Errorf("failed to update article: %w", err)
+	}
+
+	// Keep the approved version - the diff base when the article comes back as an update
+	if err := s.versions.Save(pending.Article); err != nil {
+		log.Printf("Warning: Failed to store approved version: %v", err)
 	}
 
 	// Clean up preview files
@@ -274,57 +587,86 @@ func (s *Server) handleApproveAndDeploy(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// 2. Build full Hugo site
-	publicDir, mirrorDir, err := s.hugoBuilder.BuildFullSite()
-	if err != nil {
-		log.Printf("Error building site: %v", err)
-		http.Error(w, "Failed to build site", http.StatusInternalServerError)
-		return
+	if s.schedule(pending) {
+		// Not deployed now even with deployNow - the periodic build includes it after publish_at
+		go s.translateArticle(id)
+		return true, nil
 	}
 
-	// 3. Deploy (mirror-sync + git + rsync)
-	if err := s.deployer.Deploy(publicDir, mirrorDir); err != nil {
-		log.Printf("Error deploying: %v", err)
-		http.Error(w, "Failed to deploy site", http.StatusInternalServerError)
-		return
+	if !deployNow {
+		// Translate in background - picked up by the next periodic build
+		go s.translateArticle(id)
+		return true, nil
 	}
 
-	fmt.Fprintf(w, `
-		<!DOCTYPE html>
-		<html><head><meta charset="UTF-8"><title>Deployeret</title></head>
-		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
-			<h1>⚡ Artikel Godkendt & Deployeret!</h1>
-			<p>Artiklen er nu live på norsetinge.com</p>
-			<p style="color: #666; font-size: 14px;">Bygget, deployeret og arkiveret i udgivet/</p>
-		</body></html>
-	`)
+	// Translate before building so the deploy includes all languages
+	s.translateArticle(id)
+
+	return true, s.BuildAndDeploy()
 }
 
-// handleReject handles rejection action
-func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[len("/action/reject/"):]
+// schedule moves an approved article with a future publish_at to Scheduled
+func (s *Server) schedule(entry *PendingArticle) bool {
+	if !entry.Article.IsScheduled(time.Now()) {
+		return false
+	}
 
-	s.mu.Lock()
-	pending, exists := s.pendingArticles[id]
-	if !exists {
-		s.mu.Unlock()
-		http.Error(w, "Article not found", http.StatusNotFound)
-		return
+	s.transition(entry.ID, StateScheduled, "publish_at "+entry.Article.PublishAt.Format(time.RFC3339))
+	log.Printf("⏰ Scheduled: %s - publishes after %s", entry.Article.Title, entry.Article.PublishAt.Format("2006-01-02 15:04"))
+	return true
+}
+
+// publishApproved sets status published, stamps the publication date and moves the file to udgivet/.
+// Safe to repeat when resuming after a crash.
+func (s *Server) publishApproved(article *common.Article) error {
+	changed := false
+	if article.GetCurrentStatus() != "published" {
+		article.UpdateStatus("published")
+		changed = true
+	}
+	// Dated by the approval, before the build; scheduled articles are stamped when they go live
+	now := time.Now()
+	if !article.IsScheduled(now) && builder.StampPublication(s.cfg, article, now) {
+		changed = true
+	}
+	if changed {
+		if err := article.WriteFrontmatter(); err != nil {
+			return err
+		}
 	}
-	delete(s.pendingArticles, id)
-	s.mu.Unlock()
 
-	// Persist the change to the pending articles list on disk
-	if err := s.savePendingArticles(); err != nil {
-		log.Printf("Warning: Failed to save pending articles list: %v", err)
+	if s.mover != nil {
+		if err := s.mover.MoveArticle(article); err != nil {
+			log.Printf("Error moving article: %v", err)
+		} else {
+			log.Printf("✓ Article moved to udgivet/: %s", article.Title)
+		}
 	}
 
+	// Remember the new location
+	s.storeArticle(article)
+	return nil
+}
+
+// storeArticle saves a changed copy of an article (status, location) in the flow
+func (s *Server) storeArticle(article *common.Article) {
+	if err := s.flow.Update(article.ID, func(p *PendingArticle) { p.Article = article.Clone() }); err != nil {
+		log.Printf("Warning: Failed to save publish flow: %v", err)
+	}
+}
+
+// reject moves a pending article to afvist/
+func (s *Server) reject(id, editor string) error {
+	if err := s.flow.TransitionBy(id, StateRejected, editor, ""); err != nil {
+		return err
+	}
+
+	pending, _ := s.flow.Get(id)
+
 	// Update article status to rejected and move file
 	pending.Article.UpdateStatus("rejected")
 	if err := pending.Article.WriteFrontmatter(); err != nil {
-		log.Printf("Error updating article status: %v", err)
-		http.Error(w, "Failed to update article", http.StatusInternalServerError)
-		return
+		return fmt.Errorf("failed to update article: %w", err)
 	}
 
 	// Move file to afvist/
@@ -333,8 +675,9 @@ func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Error moving article: %v", err)
 		}
 	}
+	s.storeArticle(pending.Article)
 
-	log.Printf("Article rejected: %s", pending.Article.Title)
+	log.Printf("Article rejected by %s: %s", editorName(editor), pending.Article.Title)
 
 	// Clean up preview files
 	s.cleanupPreviewFiles(pending.Article)
@@ -346,14 +689,321 @@ func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	fmt.Fprintf(w, `
-		<!DOCTYPE html>
-		<html><head><meta charset="UTF-8"><title>Afvist</title></head>
-		<body style="font-family: sans-serif; max-width: 600px; margin: 50px auto; text-align: center;">
-			<h1>❌ Artikel Afvist</h1>
-			<p>Artiklen er flyttet til afvist/ mappen.</p>
-		</body></html>
-	`)
+	return nil
+}
+
+// revise stores the editor's comments in the article, sets status revision
+// (moving it to afventer-rettelser/) and notifies the author
+func (s *Server) revise(id, editor, comments string) error {
+	if err := s.flow.TransitionBy(id, StateRevisionRequested, editor, ""); err != nil {
+		return err
+	}
+
+	if err := s.flow.Update(id, func(p *PendingArticle) { p.Comments = comments }); err != nil {
+		log.Printf("Warning: Failed to save publish flow: %v", err)
+	}
+
+	pending, _ := s.flow.Get(id)
+	article := pending.Article
+
+	article.EditorComments = comments
+	article.UpdateStatus("revision")
+	if err := article.WriteFrontmatter(); err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+
+	// Move file to afventer-rettelser/
+	if s.mover != nil {
+		if err := s.mover.MoveArticle(article); err != nil {
+			log.Printf("Error moving article: %v", err)
+		}
+	}
+	s.storeArticle(article)
+
+	log.Printf("Revision requested by %s: %s", editorName(editor), article.Title)
+
+	s.cleanupPreviewFiles(article)
+
+	if s.cfg.Ntfy.Enabled {
+		if err := s.ntfySender.ClearAllNotifications(); err != nil {
+			log.Printf("Warning: Failed to clear ntfy notifications: %v", err)
+		}
+		if err := s.ntfySender.SendRevisionNotification(article.Title, article.Author, comments); err != nil {
+			log.Printf("Warning: Failed to send ntfy revision notification: %v", err)
+		}
+	}
+
+	if s.cfg.Email.Enabled {
+		if to := s.authorAddress(article.Author); to == "" {
+			log.Printf("Warning: No email address for author %q - add it under email.authors", article.Author)
+		} else if err := s.emailSender.SendRevisionEmail(article, to, comments); err != nil {
+			log.Printf("Warning: Failed to email author: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Unpublish takes an article down: the takedown is recorded in the flow, the next build
+// replaces the article with a tombstone page, and that build is deployed immediately.
+// Does nothing if the article is already unpublished.
+func (s *Server) Unpublish(article *common.Article) error {
+	changed, err := s.flow.Unpublish(article, "status unpublish")
+	if err != nil {
+		return fmt.Errorf("failed to record unpublish: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	log.Printf("🗑️ Unpublishing: %s (%s) - deploying now", article.Title, article.ID)
+	s.cleanupPreviewFiles(article)
+
+	if err := s.BuildAndDeploy(); err != nil {
+		return fmt.Errorf("failed to deploy takedown (retried by the next periodic build): %w", err)
+	}
+	return nil
+}
+
+// BuildAndDeploy builds and deploys the site now, through the scheduler so it never
+// overlaps with a scheduled build. Used by approve+deploy, unpublish and the API.
+func (s *Server) BuildAndDeploy() error {
+	return s.scheduler.BuildNow()
+}
+
+// Build builds the site without deploying, through the scheduler so it never rewrites
+// public/ while a deploy is reading it. The next scheduled run deploys the build.
+func (s *Server) Build() error {
+	return s.scheduler.Build(func() error {
+		_, err := s.build()
+		return err
+	})
+}
+
+// DryRun reports what deploying the last build (hugo.public_dir) would change, like the
+// dry-run command. Nothing is built or written; it waits for a running build.
+func (s *Server) DryRun() (*deployer.DeployReport, error) {
+	var report *deployer.DeployReport
+	err := s.scheduler.Exclusive("dry-run", func() error {
+		var err error
+		report, err = s.deployer.Plan(s.cfg.Hugo.PublicDir, s.cfg.Hugo.MirrorDir)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.runMu.Lock()
+	s.lastPlan = report
+	s.runMu.Unlock()
+	return report, nil
+}
+
+// ConfirmDeploy builds and deploys, allowing up to deletes files to be deleted even
+// above deploy.max_deletes. Used after reviewing the dry-run of a stopped deploy.
+func (s *Server) ConfirmDeploy(deletes int, editor string) error {
+	log.Printf("🗑️  Up to %d deletes confirmed by %s", deletes, editorName(editor))
+	s.deployer.AllowDeletes(deletes)
+	return s.BuildAndDeploy()
+}
+
+// Deploys lists the newest deploys in the mirror's git history, newest first
+func (s *Server) Deploys(limit int) ([]deployer.DeployCommit, error) {
+	return s.deployer.ListDeploys(s.cfg.Hugo.MirrorDir, limit)
+}
+
+// Rollback deploys the site of an earlier deploy commit again. It waits for a running
+// build, and the following builds skip until published content changes.
+func (s *Server) Rollback(commit, editor string) (*deployer.DeployCommit, error) {
+	var restored *deployer.DeployCommit
+	err := s.scheduler.Exclusive("rollback", func() error {
+		start := time.Now()
+		var err error
+		restored, err = s.deployer.Rollback(s.cfg.Hugo.MirrorDir, commit)
+		s.recordRun(&s.lastDeploy, start, err)
+		if err == nil {
+			s.runMu.Lock()
+			s.lastDeploy.Rollback = restored
+			s.runMu.Unlock()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("⏪ Rollback to %s by %s", restored.ShortHash(), editorName(editor))
+	return restored, nil
+}
+
+// Scheduler returns the build scheduler, to be started and fed file events by main.go
+func (s *Server) Scheduler() *builder.Scheduler {
+	return s.scheduler
+}
+
+// buildAndDeploy builds the full site, deploys it and advances deployed articles in the flow
+func (s *Server) buildAndDeploy() error {
+	buildStart := time.Now()
+
+	result, err := s.build()
+	if err != nil {
+		return err
+	}
+
+	// Deploy (mirror-sync + git + rsync)
+	deployStart := time.Now()
+	err = s.deployer.Deploy(result.PublicDir, result.MirrorDir)
+	s.recordRun(&s.lastDeploy, deployStart, err)
+
+	var thresholdErr *deployer.DeleteThresholdError
+	s.runMu.Lock()
+	if errors.As(err, &thresholdErr) {
+		s.lastPlan = thresholdErr.Report
+		s.pendingDeletes = thresholdErr.Report
+	} else if err == nil {
+		s.pendingDeletes = nil
+	}
+	s.runMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to deploy site: %w", err)
+	}
+
+	s.markDeployed(buildStart)
+	return nil
+}
+
+// build builds the full site and records the result
+func (s *Server) build() (*builder.BuildResult, error) {
+	start := time.Now()
+
+	result, err := s.hugoBuilder.BuildFullSite()
+	s.recordRun(&s.lastBuild, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build site: %w", err)
+	}
+
+	s.runMu.Lock()
+	s.lastBuild.Changes = result
+	s.runMu.Unlock()
+	return result, nil
+}
+
+// recordRun stores the outcome of a build or deploy for the dashboard
+func (s *Server) recordRun(result **runResult, start time.Time, err error) {
+	run := &runResult{Finished: time.Now(), Duration: time.Since(start).Round(100 * time.Millisecond)}
+	if err != nil {
+		run.Err = err.Error()
+	}
+
+	s.runMu.Lock()
+	*result = run
+	s.runMu.Unlock()
+}
+
+// markDeployed advances articles approved before the build started
+func (s *Server) markDeployed(buildStart time.Time) {
+	for _, entry := range s.flow.InState(StateApproved, StateTranslating, StateScheduled, StateDeployedToMirror) {
+		if entry.approvedAt().After(buildStart) {
+			continue // Approved during the build - not included yet
+		}
+		if entry.Article != nil && entry.Article.IsScheduled(buildStart) {
+			continue // publish_at not reached - left out of the build
+		}
+
+		if entry.State != StateDeployedToMirror {
+			s.transition(entry.ID, StateDeployedToMirror, "")
+		}
+		if len(s.cfg.DeployMethods()) > 0 {
+			s.transition(entry.ID, StateDeployedToWebhost, "")
+		}
+	}
+}
+
+// approvedAt returns when the article was last approved
+func (p *PendingArticle) approvedAt() time.Time {
+	for i := len(p.History) - 1; i >= 0; i-- {
+		if p.History[i].State == StateApproved {
+			return p.History[i].Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// translateArticle translates an approved article to all configured languages.
+// Translation failures are logged but never block publishing of the original.
+func (s *Server) translateArticle(id string) {
+	if !s.translator.Enabled() {
+		log.Printf("ℹ️  Translation disabled (no OpenRouter API key or languages configured)")
+		return
+	}
+
+	pending, exists := s.flow.Get(id)
+	if !exists {
+		return
+	}
+
+	if pending.State == StateApproved {
+		s.transition(id, StateTranslating, "")
+	}
+
+	log.Printf("🌍 Translating: %s", pending.Article.Title)
+	translations, err := s.translator.TranslateAll(pending.Article)
+	if err != nil {
+		log.Printf("Warning: Some translations failed for %s: %v", pending.Article.Title, err)
+	}
+	log.Printf("✓ %d translations written for: %s", len(translations), pending.Article.Title)
+
+	if err := s.flow.Update(id, func(p *PendingArticle) { p.TranslationsDone = true }); err != nil {
+		log.Printf("Warning: Failed to save publish flow: %v", err)
+	}
+}
+
+// transition changes state and logs instead of failing - for follow-up steps
+// where the article has already been claimed
+func (s *Server) transition(id string, to ApprovalState, note string) {
+	if err := s.flow.Transition(id, to, note); err != nil {
+		log.Printf("Warning: Failed to move %s to %s: %v", id, to, err)
+	}
+}
+
+// Resume continues articles that were mid-flow when the process stopped.
+// Approved articles are moved, so call it after SetMover.
+func (s *Server) Resume() {
+	for _, entry := range s.flow.InState(StatePreviewBuilding, StateApproved, StateTranslating, StateScheduled) {
+		entry := entry
+		log.Printf("🔄 Resuming %s (%s) from state %s", entry.ID, entry.Article.Title, entry.State)
+
+		switch entry.State {
+		case StatePreviewBuilding:
+			article, err := common.ParseArticle(entry.Article.FilePath)
+			if err != nil {
+				log.Printf("Warning: Cannot resume %s: %v", entry.ID, err)
+				s.transition(entry.ID, StateIDGenerated, fmt.Sprintf("resume failed: %v", err))
+				continue
+			}
+			s.previews.Enqueue(article)
+
+		case StateApproved:
+			go func() {
+				if err := s.publishApproved(entry.Article); err != nil {
+					log.Printf("Warning: Failed to resume approval of %s: %v", entry.ID, err)
+					return
+				}
+				s.schedule(entry)
+				s.translateArticle(entry.ID)
+			}()
+
+		case StateTranslating, StateScheduled:
+			if !entry.TranslationsDone {
+				go s.translateArticle(entry.ID)
+			}
+		}
+	}
+}
+
+// normalizeID accepts IDs with or without '#' and in any case ("abc123" → "#ABC123")
+func normalizeID(id string) string {
+	return "#" + strings.ToUpper(strings.TrimPrefix(id, "#"))
 }
 
 // generateID generates a random ID
@@ -365,36 +1015,71 @@ func generateID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// getPendingArticlesPath returns the path to pending articles storage file
+// getPublishFlowPath returns the path to the publish flow journal
+func (s *Server) getPublishFlowPath() string {
+	return filepath.Join(s.cfg.Dropbox.BasePath, "publish-flow.json")
+}
+
+// getVersionsPath returns the directory of approved article versions
+func (s *Server) getVersionsPath() string {
+	return filepath.Join(s.cfg.Dropbox.BasePath, ".versions")
+}
+
+// getPendingArticlesPath returns the path to the legacy pending articles file
 func (s *Server) getPendingArticlesPath() string {
 	return filepath.Join(s.cfg.Dropbox.BasePath, ".pending_approvals.json")
 }
 
-// savePendingArticles persists pending articles to disk (with locking)
-func (s *Server) savePendingArticles() error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.savePendingArticlesNoLock()
-}
+// migratePendingArticles imports the legacy .pending_approvals.json into an empty journal
+func (s *Server) migratePendingArticles() error {
+	if _, err := os.Stat(s.getPublishFlowPath()); err == nil {
+		return nil // Journal already exists
+	}
 
-// savePendingArticlesNoLock persists pending articles to disk (assumes caller holds lock)
-func (s *Server) savePendingArticlesNoLock() error {
-	data, err := json.MarshalIndent(s.pendingArticles, "", "  ")
+	data, err := os.ReadFile(s.getPendingArticlesPath())
 	if err != nil {
-		return fmt.Errorf("failed to marshal pending articles: %w", err)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read pending articles file: %w", err)
 	}
 
-	path := s.getPendingArticlesPath()
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write pending articles file: %w", err)
+	var legacy map[string]*PendingArticle
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("failed to unmarshal pending articles: %w", err)
 	}
 
-	log.Printf("Saved %d pending articles to %s", len(s.pendingArticles), path)
+	for id, pending := range legacy {
+		if pending.Article == nil || !pending.NotificationSent {
+			continue // Placeholder that never finished - the folder scan re-requests it
+		}
+		if err := s.flow.Begin(pending.Article); err != nil {
+			return err
+		}
+		previewPath := pending.PreviewPath
+		s.flow.Update(id, func(p *PendingArticle) {
+			p.PreviewPath = previewPath
+			p.NotificationSent = true
+		})
+		s.transition(id, StatePendingApproval, "migrated from .pending_approvals.json")
+	}
+
+	log.Printf("Migrated %d pending articles from %s", len(legacy), s.getPendingArticlesPath())
 	return nil
 }
 
-// cleanupPreviewFiles removes preview files from public and mirror directories
+// cleanupPreviewFiles removes an article's preview workspace, and the preview files
+// that builds before preview workspaces left in content, public and mirror
 func (s *Server) cleanupPreviewFiles(article *common.Article) {
+	workspace := s.hugoBuilder.PreviewWorkspace(article)
+	if _, err := os.Stat(workspace); err == nil {
+		if err := os.RemoveAll(workspace); err != nil {
+			log.Printf("Warning: Failed to remove preview workspace: %v", err)
+		} else {
+			log.Printf("🧹 Cleaned up preview workspace: %s", workspace)
+		}
+	}
+
 	slug := article.GetSlug()
 	previewDirName := fmt.Sprintf("preview-%s", slug)
 
@@ -402,16 +1087,12 @@ func (s *Server) cleanupPreviewFiles(article *common.Article) {
 	publicPreviewPath := filepath.Join(s.cfg.Hugo.PublicDir, previewDirName)
 	if err := os.RemoveAll(publicPreviewPath); err != nil && !os.IsNotExist(err) {
 		log.Printf("Warning: Failed to remove preview from public: %v", err)
-	} else if err == nil {
-		log.Printf("🧹 Cleaned up preview from public/: %s", previewDirName)
 	}
 
 	// Clean up from mirror directory
 	mirrorPreviewPath := filepath.Join(s.cfg.Hugo.MirrorDir, previewDirName)
 	if err := os.RemoveAll(mirrorPreviewPath); err != nil && !os.IsNotExist(err) {
 		log.Printf("Warning: Failed to remove preview from mirror: %v", err)
-	} else if err == nil {
-		log.Printf("🧹 Cleaned up preview from mirror/: %s", previewDirName)
 	}
 
 	// Also clean up the temporary content file if it still exists
@@ -421,31 +1102,6 @@ func (s *Server) cleanupPreviewFiles(article *common.Article) {
 	}
 }
 
-// loadPendingArticles loads pending articles from disk
-func (s *Server) loadPendingArticles() error {
-	path := s.getPendingArticlesPath()
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist yet, not an error
-			return nil
-		}
-		return fmt.Errorf("failed to read pending articles file: %w", err)
-	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if err := json.Unmarshal(data, &s.pendingArticles); err != nil {
-		return fmt.Errorf("failed to unmarshal pending articles: %w", err)
-	}
-
-	log.Printf("Loaded %d pending articles from %s", len(s.pendingArticles), path)
-	return nil
-}
-
-
 const approvalTemplate = `
 <!DOCTYPE html>
 <html lang="da">
@@ -491,6 +1147,34 @@ const approvalTemplate = `
         .approve { background: #28a745; color: white; }
         .approve-deploy { background: #ff9800; color: white; }
         .reject { background: #dc3545; color: white; }
+        .revise { background: #2196F3; color: white; }
+        .revise-form {
+            margin-top: 30px;
+            padding-top: 20px;
+            border-top: 1px solid #ddd;
+        }
+        .revise-form textarea {
+            width: 100%;
+            min-height: 120px;
+            padding: 10px;
+            font-family: inherit;
+            font-size: 16px;
+            border: 1px solid #ccc;
+            border-radius: 4px;
+            box-sizing: border-box;
+            margin-bottom: 10px;
+        }
+        .diff pre {
+            background: #fafafa;
+            border: 1px solid #ddd;
+            border-radius: 4px;
+            padding: 10px;
+            overflow-x: auto;
+            font-size: 14px;
+        }
+        .diff .add { background: #e6ffed; color: #22863a; }
+        .diff .del { background: #ffeef0; color: #b31d28; }
+        .diff .skip { color: #999; }
         .info-box {
             background: #e7f3ff;
             border-left: 4px solid #2196F3;
@@ -505,10 +1189,39 @@ const approvalTemplate = `
         <h1>📰 Artikel til Godkendelse</h1>
         <p><strong>Titel:</strong> {{.Article.Title}}</p>
         <p><strong>Forfatter:</strong> {{.Article.Author}}</p>
+        {{if .Editor}}<p><strong>Redaktør:</strong> {{.Editor}}</p>{{end}}
+        {{if not .Article.PublishAt.IsZero}}<p><strong>⏰ Planlagt udgivelse:</strong> {{formatTime .Article.PublishAt}} - godkendte artikler venter til da</p>{{end}}
+    </div>
+
+    {{if gt .Required 1}}
+    <div class="info-box approvals">
+        <strong>👥 Godkendelser: {{len .Approvals}} af {{.Required}}</strong>
+        {{if .Approvals}}
+        <ul>
+            {{range .Approvals}}<li>{{.Editor}} ({{formatTime .Timestamp}})</li>{{end}}
+        </ul>
+        {{else}}
+        <p>Ingen endnu. Artiklen udgives først når {{.Required}} forskellige redaktører har godkendt.</p>
+        {{end}}
+    </div>
+    {{end}}
+
+    {{with .Diff}}
+    <div class="diff">
+        <h2>🔍 Ændringer siden godkendt version ({{formatTime .Since}})</h2>
+        {{if .Changed}}
+        <h3>Frontmatter</h3>
+        <pre>{{range .Frontmatter}}{{template "diffLine" .}}{{end}}</pre>
+        <h3>Indhold</h3>
+        <pre>{{range .Body}}{{template "diffLine" .}}{{end}}</pre>
+        {{else}}
+        <p>Ingen ændringer i frontmatter eller indhold.</p>
+        {{end}}
     </div>
+    {{end}}
 
     <div class="info-box">
-        <strong>💡 Tip:</strong> Hvis artiklen skal rettes, afvis den. Du kan derefter rette den i <code>afvist/</code> mappen og sætte <code>update: 1</code> for at sende den til godkendelse igen.
+        <strong>💡 Tip:</strong> Skriv dine rettelser nederst og send dem til forfatteren. Artiklen flyttes til <code>afventer-rettelser/</code> med dine kommentarer i <code>editor_comments</code>, og forfatteren sætter <code>update: 1</code> for at sende den til godkendelse igen.
     </div>
 
     <div class="article-preview">
@@ -517,10 +1230,35 @@ const approvalTemplate = `
     </div>
 
     <div class="actions">
-        <a href="/action/approve/{{.ID}}" class="button approve">✅ Godkend</a>
-        <a href="/action/approve-deploy/{{.ID}}" class="button approve-deploy" onclick="return confirm('Deploy øjeblikkeligt?\n\nArtiklen vil blive bygget og deployeret med det samme.')">⚡ Godkend + Deploy Nu</a>
-        <a href="/action/reject/{{.ID}}" class="button reject" onclick="return confirm('Afvis artikel?\n\nDu kan rette den i afvist/ mappen.')">❌ Afvis</a>
+        <form method="POST" action="/action/approve/{{.URLID}}">
+            <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+            <input type="hidden" name="editor" value="{{.Editor}}">
+            <button type="submit" class="button approve">✅ Godkend</button>
+        </form>
+        <form method="POST" action="/action/approve-deploy/{{.URLID}}" onsubmit="return confirm('Deploy øjeblikkeligt?\n\nArtiklen vil blive bygget og deployeret med det samme.')">
+            <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+            <input type="hidden" name="editor" value="{{.Editor}}">
+            <button type="submit" class="button approve-deploy">⚡ Godkend + Deploy Nu</button>
+        </form>
+        <form method="POST" action="/action/reject/{{.URLID}}" onsubmit="return confirm('Afvis artikel?\n\nDu kan rette den i afvist/ mappen.')">
+            <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+            <input type="hidden" name="editor" value="{{.Editor}}">
+            <button type="submit" class="button reject">❌ Afvis</button>
+        </form>
     </div>
+
+    <form class="revise-form" method="POST" action="/action/revise/{{.URLID}}">
+        <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+        <input type="hidden" name="editor" value="{{.Editor}}">
+        <h2>✏️ Foreslå rettelser</h2>
+        <textarea name="comments" placeholder="Hvad skal rettes?" required></textarea>
+        <button type="submit" class="button revise">✏️ Send til rettelse</button>
+    </form>
 </body>
 </html>
+{{define "diffLine"}}{{if eq .Op "+"}}<span class="add">+ {{.Text}}</span>
+{{else if eq .Op "-"}}<span class="del">- {{.Text}}</span>
+{{else if eq .Op "…"}}<span class="skip">…</span>
+{{else}}  {{.Text}}
+{{end}}{{end}}
 `
@@ -1,11 +1,14 @@
 package approval
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
-	n"norsetinge/src/common"
+	"norsetinge/src/common"
 	"norsetinge/src/config"
 )
 
@@ -27,8 +30,8 @@ func TestNewServer(t *testing.T) {
 		t.Error("Config not set correctly")
 	}
 
-	if server.pendingArticles == nil {
-		t.Error("pendingArticles map not initialized")
+	if server.flow == nil {
+		t.Error("publish flow not initialized")
 	}
 }
 
@@ -95,6 +98,16 @@ func TestRequestApproval(t *testing.T) {
 		t.Logf("RequestApproval returned expected error: %v", err)
 	}
 
+	// The preview is built in the background - and fails without hugo
+	server.WaitForPreviews()
+	jobs := server.PreviewJobs()
+	if len(jobs) != 1 || jobs[0].ID != "#TEST01" || jobs[0].State != PreviewFailed {
+		t.Errorf("Expected one failed preview job, got %+v", jobs)
+	}
+	if entry, _ := server.flow.Get("#TEST01"); entry.State != StateIDGenerated {
+		t.Errorf("Expected IDGenerated after failed preview, got %s", entry.State)
+	}
+
 	// Even with error, check if notification was attempted
 	// (This test mainly validates that the function doesn't panic)
 }
@@ -155,6 +168,10 @@ func TestCleanupPreviewFiles(t *testing.T) {
 		t.Fatal("Content preview file should exist before cleanup")
 	}
 
+	// ... and the preview workspace
+	workspace := server.hugoBuilder.PreviewWorkspace(article)
+	os.MkdirAll(filepath.Join(workspace, "public"), 0755)
+
 	// Run cleanup
 	server.cleanupPreviewFiles(article)
 
@@ -169,5 +186,52 @@ func TestCleanupPreviewFiles(t *testing.T) {
 		t.Error("Content preview file should be removed after cleanup")
 	}
 
+	if _, err := os.Stat(workspace); !os.IsNotExist(err) {
+		t.Error("Preview workspace should be removed after cleanup")
+	}
+
 	t.Log("✅ Bug 9 fix verified: Preview files cleaned up successfully")
 }
+
+func TestPreviewServedFromWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+	}
+	server := NewServer(cfg)
+
+	previewPage := filepath.Join(server.hugoBuilder.PreviewPublicDir("test01"), "test-article", "index.html")
+	os.MkdirAll(filepath.Dir(previewPage), 0755)
+	os.WriteFile(previewPage, []byte("preview"), 0644)
+
+	os.MkdirAll(cfg.Hugo.PublicDir, 0755)
+	os.WriteFile(filepath.Join(cfg.Hugo.PublicDir, "sitemap.xml"), []byte("production"), 0644)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/preview/test01/test-article/", http.StatusOK},
+		{"/preview/sitemap.xml", http.StatusNotFound}, // Production public/ is not served
+		{"/preview/test01/../../public/sitemap.xml", http.StatusNotFound},
+		{"/preview/TEST01/test-article/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.handlePreview(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.code, rec.Code)
+		}
+	}
+
+	if strings.HasPrefix(server.hugoBuilder.PreviewDir(), cfg.Hugo.PublicDir) {
+		t.Errorf("Preview dir %s is inside the public dir", server.hugoBuilder.PreviewDir())
+	}
+}
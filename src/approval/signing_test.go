@@ -0,0 +1,153 @@
+package approval
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestSignedLink(t *testing.T) {
+	signer := &linkSigner{key: []byte("test-key"), ttl: time.Hour}
+
+	query, _ := url.ParseQuery(signer.SignedQuery("#ABC123", ""))
+	if err := signer.VerifyLink("abc123", query); err != nil {
+		t.Errorf("Valid link rejected: %v", err)
+	}
+
+	if err := signer.VerifyLink("#ABC124", query); err != errLinkInvalid {
+		t.Errorf("Link for another article: expected errLinkInvalid, got %v", err)
+	}
+
+	tampered, _ := url.ParseQuery(query.Encode())
+	tampered.Set("exp", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+	if err := signer.VerifyLink("#ABC123", tampered); err != errLinkInvalid {
+		t.Errorf("Extended expiry: expected errLinkInvalid, got %v", err)
+	}
+
+	if err := signer.VerifyLink("#ABC123", url.Values{}); err != errLinkInvalid {
+		t.Errorf("Unsigned link: expected errLinkInvalid, got %v", err)
+	}
+
+	other := &linkSigner{key: []byte("other-key"), ttl: time.Hour}
+	if err := other.VerifyLink("#ABC123", query); err != errLinkInvalid {
+		t.Errorf("Other key: expected errLinkInvalid, got %v", err)
+	}
+
+	expired := &linkSigner{key: []byte("test-key"), ttl: -time.Minute}
+	query, _ = url.ParseQuery(expired.SignedQuery("#ABC123", ""))
+	if err := signer.VerifyLink("#ABC123", query); err != errLinkExpired {
+		t.Errorf("Expired link: expected errLinkExpired, got %v", err)
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	signer := &linkSigner{key: []byte("test-key"), ttl: time.Hour}
+
+	token := signer.CSRFToken("#ABC123", "")
+	if err := signer.VerifyCSRF("ABC123", "", token); err != nil {
+		t.Errorf("Valid token rejected: %v", err)
+	}
+	if err := signer.VerifyCSRF("#XYZ789", "", token); err != errLinkInvalid {
+		t.Errorf("Token for another article: expected errLinkInvalid, got %v", err)
+	}
+	if err := signer.VerifyCSRF("#ABC123", "", ""); err != errLinkInvalid {
+		t.Errorf("Missing token: expected errLinkInvalid, got %v", err)
+	}
+
+	// The token binds the editor the page was rendered for
+	editorToken := signer.CSRFToken("#ABC123", "tb@github")
+	if err := signer.VerifyCSRF("#ABC123", "tb@github", editorToken); err != nil {
+		t.Errorf("Valid editor token rejected: %v", err)
+	}
+	if err := signer.VerifyCSRF("#ABC123", "mallory@github", editorToken); err != errLinkInvalid {
+		t.Errorf("Token with swapped editor: expected errLinkInvalid, got %v", err)
+	}
+
+	// A link signature is not a CSRF token
+	query, _ := url.ParseQuery(signer.SignedQuery("#ABC123", ""))
+	if err := signer.VerifyCSRF("#ABC123", "", query.Get("exp")+"."+query.Get("sig")); err != errLinkInvalid {
+		t.Errorf("Link signature as token: expected errLinkInvalid, got %v", err)
+	}
+
+	exp := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	if err := signer.VerifyCSRF("#ABC123", "", exp+"."+signer.sign("csrf", "#ABC123", "", exp)); err != errLinkExpired {
+		t.Errorf("Expired token: expected errLinkExpired, got %v", err)
+	}
+}
+
+func TestApprovalRequiresSignedLinkAndCSRF(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Approval: config.ApprovalConfig{SigningKey: "test-key"},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+	handler := server.routes()
+
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#SIG001\"\ntitle: Signed\nauthor: TB\nstatus:\n  publish: 1\n---\n\nBody\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The article ID alone does not open the approval page
+	if rec := serve(httptest.NewRequest("GET", "/approve/SIG001", nil)); rec.Code != http.StatusForbidden {
+		t.Errorf("Unsigned link: expected 403, got %d", rec.Code)
+	}
+
+	rec := serve(httptest.NewRequest("GET", server.approvalPath(article.ID, ""), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Signed link: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	token := server.signer.CSRFToken(article.ID, "")
+	if !strings.Contains(rec.Body.String(), `name="csrf_token" value="`+token[:strings.Index(token, ".")]) {
+		t.Error("Approval page forms are missing the CSRF token")
+	}
+
+	// Prefetchers and unfurlers only GET
+	for _, path := range []string{"/action/approve/SIG001", "/action/approve-deploy/SIG001", "/action/reject/SIG001"} {
+		if rec := serve(httptest.NewRequest("GET", path, nil)); rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET %s: expected 405, got %d", path, rec.Code)
+		}
+	}
+
+	// A POST without the page's token is refused
+	req := httptest.NewRequest("POST", "/action/reject/SIG001", strings.NewReader("csrf_token=123.abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec := serve(req); rec.Code != http.StatusForbidden {
+		t.Errorf("Forged token: expected 403, got %d", rec.Code)
+	}
+
+	if entry, _ := server.flow.Get(article.ID); entry.State != StatePendingApproval {
+		t.Fatalf("Expected article still pending, got %s", entry.State)
+	}
+
+	if rec := serve(actionRequest(server, "/action/reject/SIG001", url.Values{})); rec.Code != http.StatusOK {
+		t.Errorf("Valid token: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if entry, _ := server.flow.Get(article.ID); entry.State != StateRejected {
+		t.Errorf("Expected Rejected, got %s", entry.State)
+	}
+}
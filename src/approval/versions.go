@@ -0,0 +1,242 @@
+package approval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"norsetinge/src/common"
+)
+
+// versionTimeFormat names version files so they sort chronologically
+const versionTimeFormat = "20060102T150405Z"
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// VersionStore keeps every approved version of an article as a markdown file:
+// {dir}/{idslug}/{timestamp}.md
+type VersionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Version is one stored, approved version of an article
+type Version struct {
+	Approved time.Time
+	Path     string
+}
+
+// NewVersionStore creates a version store rooted at dir
+func NewVersionStore(dir string) *VersionStore {
+	return &VersionStore{dir: dir}
+}
+
+// Save stores the article as approved now
+func (v *VersionStore) Save(article *common.Article) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := article.Markdown()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(v.dir, article.GetIDSlug())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+
+	// Two approvals within a second keep the latest
+	path := filepath.Join(dir, time.Now().UTC().Format(versionTimeFormat)+".md")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write version: %w", err)
+	}
+	return nil
+}
+
+// List returns the stored versions of an article, oldest first
+func (v *VersionStore) List(id string) ([]Version, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	dir := filepath.Join(v.dir, strings.ToLower(strings.TrimPrefix(normalizeID(id), "#")))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read versions: %w", err)
+	}
+
+	var versions []Version
+	for _, entry := range entries {
+		approved, err := time.Parse(versionTimeFormat, strings.TrimSuffix(entry.Name(), ".md"))
+		if entry.IsDir() || err != nil {
+			continue
+		}
+		versions = append(versions, Version{Approved: approved, Path: filepath.Join(dir, entry.Name())})
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Approved.Before(versions[j].Approved) })
+	return versions, nil
+}
+
+// Latest returns the last approved version of an article, or nil if there is none
+func (v *VersionStore) Latest(id string) (*Version, *common.Article, error) {
+	versions, err := v.List(id)
+	if err != nil || len(versions) == 0 {
+		return nil, nil, err
+	}
+
+	latest := versions[len(versions)-1]
+	article, err := common.ParseArticle(latest.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse version %s: %w", latest.Path, err)
+	}
+	return &latest, article, nil
+}
+
+// articleDiff is the change between the last approved version and the article up for approval
+type articleDiff struct {
+	Since       time.Time
+	Frontmatter []diffLine
+	Body        []diffLine
+}
+
+// Changed reports whether anything differs
+func (d *articleDiff) Changed() bool {
+	for _, lines := range [][]diffLine{d.Frontmatter, d.Body} {
+		for _, line := range lines {
+			if line.Op != diffSame && line.Op != diffSkip {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diffOp marks a diff line as unchanged, added, removed or an elided run of unchanged lines
+type diffOp string
+
+const (
+	diffSame diffOp = " "
+	diffAdd  diffOp = "+"
+	diffDel  diffOp = "-"
+	diffSkip diffOp = "…"
+)
+
+// diffLine is one line of a rendered diff
+type diffLine struct {
+	Op   diffOp
+	Text string
+}
+
+// diffArticles compares the frontmatter and body of two versions of an article.
+// Status flags are cleared - they always differ between a published version and an update.
+func diffArticles(previous, current *common.Article, since time.Time) (*articleDiff, error) {
+	oldFM, err := diffFrontmatter(previous)
+	if err != nil {
+		return nil, err
+	}
+	newFM, err := diffFrontmatter(current)
+	if err != nil {
+		return nil, err
+	}
+
+	return &articleDiff{
+		Since:       since,
+		Frontmatter: lineDiff(oldFM, newFM),
+		Body:        lineDiff(previous.Content, current.Content),
+	}, nil
+}
+
+// diffFrontmatter returns the frontmatter YAML with status flags cleared
+func diffFrontmatter(article *common.Article) (string, error) {
+	stripped := *article
+	stripped.Status = common.Status{}
+
+	data, err := stripped.Markdown()
+	if err != nil {
+		return "", err
+	}
+	frontmatter, _, _ := strings.Cut(strings.TrimPrefix(string(data), "---\n"), "---\n")
+	return frontmatter, nil
+}
+
+// lineDiff computes a line diff (longest common subsequence) and folds unchanged runs
+// to diffContext lines around each change
+func lineDiff(oldText, newText string) []diffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{diffSame, a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{diffDel, a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{diffAdd, b[j]})
+			j++
+		}
+	}
+
+	return foldUnchanged(lines)
+}
+
+// foldUnchanged replaces unchanged lines further than diffContext from a change with one diffSkip line
+func foldUnchanged(lines []diffLine) []diffLine {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == diffSame {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(lines)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+
+	var folded []diffLine
+	for i, line := range lines {
+		if keep[i] {
+			folded = append(folded, line)
+		} else if len(folded) == 0 || folded[len(folded)-1].Op != diffSkip {
+			folded = append(folded, diffLine{Op: diffSkip})
+		}
+	}
+	return folded
+}
+
+// splitLines splits text into lines, ignoring a trailing newline
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,128 @@
+package approval
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []diffLine
+	}{
+		{"unchanged", "a\nb", "a\nb", []diffLine{{diffSkip, ""}}},
+		{"added line", "a\nb", "a\nx\nb", []diffLine{{diffSame, "a"}, {diffAdd, "x"}, {diffSame, "b"}}},
+		{"changed line", "a\nb\nc", "a\nB\nc", []diffLine{{diffSame, "a"}, {diffDel, "b"}, {diffAdd, "B"}, {diffSame, "c"}}},
+		{"new text", "", "a", []diffLine{{diffAdd, "a"}}},
+		{"folds distant context", "1\n2\n3\n4\n5\n6\n7\n8", "1\n2\n3\n4\n5\n6\n7\nX", []diffLine{
+			{diffSkip, ""}, {diffSame, "5"}, {diffSame, "6"}, {diffSame, "7"}, {diffDel, "8"}, {diffAdd, "X"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionStore(t *testing.T) {
+	store := NewVersionStore(t.TempDir())
+
+	if version, _, err := store.Latest("#VER001"); version != nil || err != nil {
+		t.Fatalf("Expected no versions, got (%v, %v)", version, err)
+	}
+
+	article := &common.Article{ID: "#VER001", Title: "First", Author: "TB", Content: "Body"}
+	if err := store.Save(article); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	version, saved, err := store.Latest("VER001")
+	if err != nil || version == nil {
+		t.Fatalf("Latest() = (%v, %v)", version, err)
+	}
+	if saved.Title != "First" || saved.Content != "Body" {
+		t.Errorf("Unexpected stored version: %+v", saved)
+	}
+}
+
+func TestUpdateApprovalShowsDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+			MirrorDir: filepath.Join(tmpDir, "mirror"),
+		},
+		Approval: config.ApprovalConfig{SigningKey: "test-key"},
+	}
+	server := NewServer(cfg)
+	server.SetMover(&recordingMover{})
+
+	articlePath := filepath.Join(tmpDir, "article.md")
+	os.WriteFile(articlePath, []byte("---\nid: \"#UPD001\"\ntitle: Original\nauthor: TB\nstatus:\n  publish: 1\n---\n\nFirst paragraph\n\nSecond paragraph\n"), 0644)
+	article, _ := common.ParseArticle(articlePath)
+
+	server.flow.Begin(article)
+	server.flow.Transition(article.ID, StatePendingApproval, "")
+
+	// A new article has nothing to compare with
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest("GET", server.approvalPath(article.ID, ""), nil))
+	if strings.Contains(rec.Body.String(), `class="diff"`) {
+		t.Error("Approval page for a new article shows a diff")
+	}
+
+	if complete, err := server.approve(article.ID, "tb", false); err != nil || !complete {
+		t.Fatalf("approve() = (%v, %v)", complete, err)
+	}
+	if versions, _ := server.versions.List(article.ID); len(versions) != 1 {
+		t.Fatalf("Expected 1 stored version, got %d", len(versions))
+	}
+	server.markDeployed(time.Now().Add(time.Second))
+
+	// The author sends it back as an update
+	os.WriteFile(articlePath, []byte("---\nid: \"#UPD001\"\ntitle: Revised\nauthor: TB\nstatus:\n  update: 1\n---\n\nFirst paragraph\n\nSecond <em>paragraph</em>\n"), 0644)
+	update, _ := common.ParseArticle(articlePath)
+	if err := server.flow.Begin(update); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	server.flow.Transition(update.ID, StatePendingApproval, "")
+
+	rec = httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest("GET", server.approvalPath(update.ID, ""), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<span class="del">- title: Original</span>`,
+		`<span class="add">+ title: Revised</span>`,
+		`<span class="del">- Second paragraph</span>`,
+		`<span class="add">+ Second &lt;em&gt;paragraph&lt;/em&gt;</span>`,
+		"  First paragraph",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Approval page diff missing %q", want)
+		}
+	}
+	if strings.Contains(body, `<span class="add">+     update`) {
+		t.Error("Status flags shown as a change")
+	}
+}
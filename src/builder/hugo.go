@@ -7,48 +7,130 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"norsetinge/src/common"
 	"norsetinge/src/config"
+	"norsetinge/src/translator"
+
+	"gopkg.in/yaml.v3"
 )
 
 // HugoBuilder handles Hugo site building
 type HugoBuilder struct {
-	cfg *config.Config
+	cfg        *config.Config
+	translator *translator.Translator
+	images     *common.ImageProcessor
+
+	// Full builds are exclusive; previews have their own workspaces and run concurrently
+	mu sync.RWMutex
+}
+
+// hugoPage is the frontmatter written to Hugo content files
+type hugoPage struct {
+	Title          string          `yaml:"title"`
+	Author         string          `yaml:"author"`
+	Description    string          `yaml:"description,omitempty"`
+	Tags           []string        `yaml:"tags,omitempty"`
+	Categories     []string        `yaml:"categories,omitempty"`
+	Images         []string        `yaml:"images,omitempty"`
+	Icons          *common.IconSet `yaml:"icons,omitempty"`
+	Date           time.Time       `yaml:"date,omitempty"`
+	Draft          bool            `yaml:"draft"`
+	Preview        bool            `yaml:"preview,omitempty"`
+	ArticleID      string          `yaml:"articleID"`
+	TranslationKey string          `yaml:"translationKey,omitempty"`
+	URL            string          `yaml:"url,omitempty"`
+	Aliases        []string        `yaml:"aliases,omitempty"`
+	Layout         string          `yaml:"layout,omitempty"`
+	Build          *pageBuild      `yaml:"build,omitempty"`
+}
+
+// pageBuild is Hugo's build options for a page
+type pageBuild struct {
+	List string `yaml:"list"` // "never" keeps the page out of lists and the sitemap
 }
 
 // NewHugoBuilder creates a new Hugo builder
 func NewHugoBuilder(cfg *config.Config) *HugoBuilder {
-	return &HugoBuilder{cfg: cfg}
+	return &HugoBuilder{
+		cfg:        cfg,
+		translator: translator.NewTranslator(cfg),
+		images:     common.NewImageProcessor(cfg),
+	}
 }
 
-// BuildPreview builds a single-article preview for approval
-// Returns the URL path relative to /preview/ endpoint (served via Tailscale)
+// BuildPreview builds a single-article preview for approval in its own workspace,
+// previews/{ID}/: the article alone as content, built to previews/{ID}/public.
+// Nothing is written to the site's content or public directories, so a preview can
+// never be deployed. Images and icons are generated into the workspace too.
+// Returns the URL path relative to the /preview/ endpoint.
 func (h *HugoBuilder) BuildPreview(article *common.Article) (string, error) {
-	// Detect article language from content or default to Danish
-	lang := h.detectLanguage(article)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	// Create content file in Hugo structure
-	slug := article.GetSlug()
-	contentPath := filepath.Join(h.cfg.Hugo.SiteDir, "content", fmt.Sprintf("preview-%s.md", slug))
+	workspace := h.PreviewWorkspace(article)
+	if err := os.RemoveAll(workspace); err != nil {
+		return "", fmt.Errorf("failed to clean preview workspace: %w", err)
+	}
+
+	// Images and icons go straight into the preview's output; the article file and
+	// site/static are left alone until the article is published
+	baseURL := fmt.Sprintf("/preview/%s/", article.GetIDSlug())
+	preview := article.Clone()
+	images := common.NewPreviewImageProcessor(h.cfg, filepath.Join(workspace, "public"), baseURL)
+	if _, err := images.Process(preview); err != nil {
+		log.Printf("Warning: Image processing failed for preview of %s: %v", article.ID, err)
+	}
 
-	// Write article as Hugo content
-	if err := h.writeHugoContent(contentPath, article, lang); err != nil {
+	// Write article as the only Hugo content
+	slug := article.GetSlug()
+	contentDir := filepath.Join(workspace, "content")
+	page := h.newHugoPage(preview)
+	page.Preview = true
+	if err := h.writeHugoContent(filepath.Join(contentDir, slug+".md"), page, article.Content); err != nil {
 		return "", fmt.Errorf("failed to write Hugo content: %w", err)
 	}
-	defer os.Remove(contentPath) // Clean up after build
 
-	// Build Hugo site
-	if err := h.buildSite(); err != nil {
-		return "", fmt.Errorf("failed to build Hugo site: %w", err)
+	// Build into the workspace, with links relative to where the approval server serves it.
+	// No build lock and a private cache, so previews of different articles can run at once.
+	if err := h.runHugo(filepath.Join(workspace, "public"),
+		"--contentDir", contentDir,
+		"--baseURL", baseURL,
+		"--cacheDir", filepath.Join(workspace, "cache"),
+		"--noBuildLock",
+	); err != nil {
+		return "", fmt.Errorf("failed to build Hugo preview: %w", err)
 	}
 
 	// Return URL path for preview (served via /preview/ endpoint)
-	previewURLPath := fmt.Sprintf("preview-%s/index.html", slug)
+	previewURLPath := fmt.Sprintf("%s/%s/index.html", article.GetIDSlug(), slug)
 	log.Printf("Preview built and ready at: /preview/%s", previewURLPath)
 	return previewURLPath, nil
 }
 
+// PreviewDir returns the folder holding preview workspaces: hugo.preview_dir,
+// or previews/ next to the site directory. It must not be inside public_dir or mirror_dir.
+func (h *HugoBuilder) PreviewDir() string {
+	if h.cfg.Hugo.PreviewDir != "" {
+		return h.cfg.Hugo.PreviewDir
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(h.cfg.Hugo.SiteDir)), "previews")
+}
+
+// PreviewWorkspace returns the preview workspace of an article: {PreviewDir}/{ID}
+func (h *HugoBuilder) PreviewWorkspace(article *common.Article) string {
+	return filepath.Join(h.PreviewDir(), article.GetIDSlug())
+}
+
+// PreviewPublicDir returns the folder served at /preview/: {ID}/public of each workspace
+// is reached as /preview/{ID}/...
+func (h *HugoBuilder) PreviewPublicDir(idSlug string) string {
+	return filepath.Join(h.PreviewDir(), idSlug, "public")
+}
+
 // copyPreviewToDropbox copies complete Hugo preview directory to Dropbox
 func (h *HugoBuilder) copyPreviewToDropbox(src, dst string) error {
 	// Remove existing Dropbox preview if it exists
@@ -103,18 +185,49 @@ func copyFile(src, dst string, mode os.FileMode) error {
 	return err
 }
 
-// writeHugoContent writes article in Hugo content format
-func (h *HugoBuilder) writeHugoContent(path string, article *common.Article, lang string) error {
-	content := fmt.Sprintf(`---
-title: "%s"
-author: "%s"
-draft: false
-preview: true
-articleID: "%s"
----
+// newHugoPage creates Hugo frontmatter from an article
+func (h *HugoBuilder) newHugoPage(article *common.Article) *hugoPage {
+	return &hugoPage{
+		Title:       article.Title,
+		Author:      article.Author,
+		Description: article.Description,
+		Tags:        article.Tags,
+		Categories:  article.Categories,
+		Images:      pageImages(article),
+		Icons:       article.ProcessedIcons,
+		Draft:       false,
+		ArticleID:   article.ID,
+	}
+}
 
-%s
-`, article.Title, article.Author, article.ID, article.Content)
+// newPublishedPage creates frontmatter for one language version of a published article.
+// URLs are built from the source article's slug so all languages share it:
+// /artikel/{lang}/{slug}/, with English also reachable at /artikel/{slug}/
+func (h *HugoBuilder) newPublishedPage(source, version *common.Article, lang string) *hugoPage {
+	page := h.newHugoPage(version)
+	page.Images = pageImages(source)
+	page.Icons = source.ProcessedIcons
+
+	// Stamped at approval or by BuildFullSite before rendering; scheduled articles
+	// are dated by their publish_at until then
+	page.Date = source.PublicationDate
+	if page.Date.IsZero() {
+		page.Date = source.PublishAt
+	}
+	page.TranslationKey = source.GetIDSlug()
+	page.URL = source.GetURLPath(lang)
+	if lang == "en" {
+		page.Aliases = []string{fmt.Sprintf("/artikel/%s/", source.GetSlug())}
+	}
+	return page
+}
+
+// writeHugoContent writes a page in Hugo content format
+func (h *HugoBuilder) writeHugoContent(path string, page *hugoPage, body string) error {
+	content, err := renderHugoContent(page, body)
+	if err != nil {
+		return err
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -122,23 +235,62 @@ articleID: "%s"
 		return fmt.Errorf("failed to create content directory: %w", err)
 	}
 
-	return os.WriteFile(path, []byte(content), 0644)
+	return os.WriteFile(path, content, 0644)
 }
 
-// buildSite runs hugo build command
+// renderHugoContent returns a page in Hugo content format
+func renderHugoContent(page *hugoPage, body string) ([]byte, error) {
+	frontmatter, err := yaml.Marshal(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Hugo frontmatter: %w", err)
+	}
+
+	return []byte(fmt.Sprintf("---\n%s---\n\n%s\n", string(frontmatter), body)), nil
+}
+
+// processImages generates responsive image variants and icons for an article.
+// Failures are logged so a bad image never blocks a build.
+func (h *HugoBuilder) processImages(article *common.Article) {
+	if _, err := h.images.ProcessArticle(article); err != nil {
+		log.Printf("Warning: Image processing failed for %s: %v", article.ID, err)
+	}
+}
+
+// pageImages returns the og-size image URLs for Hugo's images param (used for Open Graph).
+// JPEG is preferred as the most widely supported format on social media.
+func pageImages(article *common.Article) []string {
+	var images []string
+	for _, processed := range article.ProcessedImages {
+		for _, format := range []string{"jpeg", "webp", "png"} {
+			if url := processed.Sizes["og"][format]; url != "" {
+				images = append(images, url)
+				break
+			}
+		}
+	}
+	return images
+}
+
+// buildSite runs hugo build command for the full site
 func (h *HugoBuilder) buildSite() error {
+	return h.runHugo(h.cfg.Hugo.PublicDir)
+}
+
+// runHugo builds the site source into destination, with extra hugo flags
+func (h *HugoBuilder) runHugo(destination string, extra ...string) error {
 	// Get absolute paths
 	siteDir, err := filepath.Abs(h.cfg.Hugo.SiteDir)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute site path: %w", err)
 	}
 
-	publicDir, err := filepath.Abs(h.cfg.Hugo.PublicDir)
+	destination, err = filepath.Abs(destination)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute public path: %w", err)
+		return fmt.Errorf("failed to get absolute destination path: %w", err)
 	}
 
-	cmd := exec.Command("hugo", "--source", siteDir, "--destination", publicDir)
+	args := append([]string{"--source", siteDir, "--destination", destination}, extra...)
+	cmd := exec.Command("hugo", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("Hugo build error: %s", string(output))
@@ -150,90 +302,262 @@ func (h *HugoBuilder) buildSite() error {
 
 // detectLanguage detects article language from frontmatter or defaults to Danish
 func (h *HugoBuilder) detectLanguage(article *common.Article) string {
-	// Check if language is specified in frontmatter
-	if article.Language != "" {
-		return article.Language
-	}
-
-	// Default to Danish (original language)
-	return "da"
+	return translator.SourceLanguage(article)
 }
 
-// BuildFullSite builds complete Hugo site with all published articles
-// Returns paths to public and mirror directories
-func (h *HugoBuilder) BuildFullSite() (publicDir, mirrorDir string, err error) {
+// BuildFullSite builds complete Hugo site with all published articles.
+// Content files are only rewritten for articles that changed since the last build
+// (tracked in site/.build-manifest.json), so unchanged pages keep their mtime.
+func (h *HugoBuilder) BuildFullSite() (*BuildResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	log.Printf("🔨 Building full site...")
 
-	// 1. Clean and prepare content directory
 	contentDir := filepath.Join(h.cfg.Hugo.SiteDir, "content", "articles")
-	if err := os.RemoveAll(contentDir); err != nil && !os.IsNotExist(err) {
-		return "", "", fmt.Errorf("failed to clean content directory: %w", err)
+	manifestPath := h.manifestPath()
+
+	// 1. Load manifest - without one, start from an empty content directory
+	previous, ok := loadManifest(manifestPath)
+	if !ok {
+		if err := os.RemoveAll(contentDir); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to clean content directory: %w", err)
+		}
 	}
 	if err := os.MkdirAll(contentDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create content directory: %w", err)
+		return nil, fmt.Errorf("failed to create content directory: %w", err)
 	}
+	h.removeLegacyPreviews()
 
-	// 2. Copy all published articles to Hugo content
-	publishedDir := filepath.Join(h.cfg.Dropbox.BasePath, "udgivet")
+	// 2. Render all published articles
+	publishedDir := h.cfg.StatusDir("published")
 	articles, err := h.loadPublishedArticles(publishedDir)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to load published articles: %w", err)
+		return nil, fmt.Errorf("failed to load published articles: %w", err)
+	}
+
+	articles, scheduled := splitScheduled(articles, time.Now())
+	for _, article := range scheduled {
+		log.Printf("⏰ Scheduled, not included yet: %s (publish_at %s)", article.Title, article.PublishAt.Format("2006-01-02 15:04"))
 	}
 
 	log.Printf("📚 Found %d published articles", len(articles))
+	h.stampPublished(articles)
 
+	wanted := make(map[string]contentFiles)
 	for _, article := range articles {
-		slug := article.GetSlug()
-		articlePath := filepath.Join(contentDir, fmt.Sprintf("%s.md", slug))
+		files, err := h.articleFiles(article)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render article %s: %w", article.ID, err)
+		}
+		wanted[article.ID] = files
+	}
 
-		if err := h.writeHugoContent(articlePath, article, h.detectLanguage(article)); err != nil {
-			return "", "", fmt.Errorf("failed to write article %s: %w", slug, err)
+	// 3. Tombstones for unpublished articles at their old URLs
+	unpublished, err := common.LoadArticles(h.cfg.StatusDir("unpublish"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unpublished articles: %w", err)
+	}
+
+	for _, article := range unpublished {
+		files, err := h.tombstoneFiles(article)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render tombstone for %s: %w", article.ID, err)
 		}
-		log.Printf("  ✓ Added: %s", article.Title)
+		wanted[article.ID] = files
 	}
 
-	// 3. Build Hugo site
+	// 4. Write only what changed
+	result := &BuildResult{}
+	manifest, err := syncContent(contentDir, previous, wanted, result)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("📝 Content: %s", result.Summary())
+
+	// 5. Build Hugo site
 	if err := h.buildSite(); err != nil {
-		return "", "", fmt.Errorf("failed to build Hugo site: %w", err)
+		return nil, fmt.Errorf("failed to build Hugo site: %w", err)
 	}
 
-	// 4. Return paths
-	publicDir = h.cfg.Hugo.PublicDir
-	mirrorDir = h.cfg.Hugo.MirrorDir
+	// Saved after a successful build, so changes are reported again if hugo failed
+	if err := manifest.save(manifestPath); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// 6. Return paths
+	result.PublicDir = h.cfg.Hugo.PublicDir
+	result.MirrorDir = h.cfg.Hugo.MirrorDir
 
 	log.Printf("✅ Full site built successfully")
-	log.Printf("   Public:  %s", publicDir)
-	log.Printf("   Mirror:  %s", mirrorDir)
+	log.Printf("   Public:  %s", result.PublicDir)
+	log.Printf("   Mirror:  %s", result.MirrorDir)
 
-	return publicDir, mirrorDir, nil
+	return result, nil
 }
 
-// loadPublishedArticles loads all articles from the published directory
-func (h *HugoBuilder) loadPublishedArticles(publishedDir string) ([]*common.Article, error) {
-	var articles []*common.Article
+// removeLegacyPreviews removes preview content and pages that were built into the site
+// before previews got their own workspaces, so they never reach the deployed output
+func (h *HugoBuilder) removeLegacyPreviews() {
+	patterns := []string{
+		filepath.Join(h.cfg.Hugo.SiteDir, "content", "preview-*.md"),
+		filepath.Join(h.cfg.Hugo.PublicDir, "preview-*"),
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			if err := os.RemoveAll(path); err != nil {
+				log.Printf("Warning: Failed to remove old preview %s: %v", path, err)
+			} else {
+				log.Printf("🧹 Removed old preview: %s", path)
+			}
+		}
+	}
+}
 
-	entries, err := os.ReadDir(publishedDir)
+// manifestPath returns where the content manifest of the last build is kept
+func (h *HugoBuilder) manifestPath() string {
+	return filepath.Join(h.cfg.Hugo.SiteDir, ".build-manifest.json")
+}
+
+// articleFiles renders an article and its translations as a Hugo page bundle:
+// {ID}/index.{lang}.md. Hugo links files in the same bundle as translations.
+func (h *HugoBuilder) articleFiles(article *common.Article) (contentFiles, error) {
+	files := contentFiles{}
+	sourceLang := h.detectLanguage(article)
+
+	h.processImages(article)
+
+	source, err := renderHugoContent(h.newPublishedPage(article, article, sourceLang), article.Content)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return articles, nil // No published articles yet
-		}
-		return nil, fmt.Errorf("failed to read published directory: %w", err)
+		return nil, err
+	}
+	files[bundlePath(article, sourceLang)] = source
+
+	translations, err := h.translator.LoadTranslations(article)
+	if err != nil {
+		log.Printf("Warning: Failed to load translations for %s: %v", article.ID, err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+	for _, translation := range translations {
+		lang := translation.Language
+		if lang == "" || lang == sourceLang {
 			continue
 		}
 
-		articlePath := filepath.Join(publishedDir, entry.Name())
-		article, err := common.ParseArticle(articlePath)
+		content, err := renderHugoContent(h.newPublishedPage(article, translation, lang), translation.Content)
 		if err != nil {
-			log.Printf("Warning: Failed to parse %s: %v", entry.Name(), err)
-			continue
+			return nil, fmt.Errorf("failed to render %s translation: %w", lang, err)
+		}
+		files[bundlePath(article, lang)] = content
+	}
+
+	return files, nil
+}
+
+// tombstoneFiles renders a "taken down" page for each of an unpublished article's old
+// URLs (layouts/_default/tombstone.html) and removes its generated images and icons
+func (h *HugoBuilder) tombstoneFiles(article *common.Article) (contentFiles, error) {
+	files := contentFiles{}
+
+	languages := []string{h.detectLanguage(article)}
+	translations, err := h.translator.LoadTranslations(article)
+	if err != nil {
+		log.Printf("Warning: Failed to load translations for %s: %v", article.ID, err)
+	}
+	for _, translation := range translations {
+		if translation.Language != "" && translation.Language != languages[0] {
+			languages = append(languages, translation.Language)
+		}
+	}
+
+	for _, lang := range languages {
+		page := &hugoPage{
+			Title:          article.Title,
+			Date:           article.PublicationDate,
+			ArticleID:      article.ID,
+			TranslationKey: article.GetIDSlug(),
+			URL:            article.GetURLPath(lang),
+			Layout:         "tombstone",
+			Build:          &pageBuild{List: "never"},
+		}
+		if lang == "en" {
+			page.Aliases = []string{fmt.Sprintf("/artikel/%s/", article.GetSlug())}
+		}
+
+		content, err := renderHugoContent(page, "")
+		if err != nil {
+			return nil, err
+		}
+		files[bundlePath(article, lang)] = content
+	}
+
+	// Taken down means the images go too
+	for _, dir := range []string{"images", "icons"} {
+		assets := filepath.Join(h.cfg.Hugo.SiteDir, "static", dir, article.GetIDSlug())
+		if err := os.RemoveAll(assets); err != nil {
+			log.Printf("Warning: Failed to remove %s: %v", assets, err)
 		}
+	}
+
+	return files, nil
+}
+
+// bundlePath returns the path of one language of an article, relative to content/articles
+func bundlePath(article *common.Article, lang string) string {
+	return fmt.Sprintf("%s/index.%s.md", article.GetIDSlug(), lang)
+}
+
+// splitScheduled separates articles whose publish_at is still in the future
+func splitScheduled(articles []*common.Article, now time.Time) (ready, scheduled []*common.Article) {
+	for _, article := range articles {
+		if article.IsScheduled(now) {
+			scheduled = append(scheduled, article)
+		} else {
+			ready = append(ready, article)
+		}
+	}
+	return ready, scheduled
+}
 
-		articles = append(articles, article)
+// StampPublication sets publication_date and publication_url on an article going live.
+// An article that is already stamped keeps its original date. It reports whether the
+// article changed; the caller writes the frontmatter.
+func StampPublication(cfg *config.Config, article *common.Article, date time.Time) bool {
+	if !article.PublicationDate.IsZero() {
+		return false
 	}
+	article.PublicationDate = date.Truncate(time.Second)
+	article.PublicationURL = strings.TrimSuffix(cfg.Hugo.BaseURL, "/") + article.GetURLPath(translator.SourceLanguage(article))
+	return true
+}
 
+// stampPublished stamps live articles that were not stamped at approval, e.g. moved to
+// udgivet/ by hand or scheduled, before they are rendered. Scheduled articles are
+// dated by their publish_at, so every build renders the same date.
+func (h *HugoBuilder) stampPublished(articles []*common.Article) {
+	now := time.Now()
+	for _, article := range articles {
+		date := article.PublishAt
+		if date.IsZero() {
+			date = now
+		}
+		if !StampPublication(h.cfg, article, date) {
+			continue
+		}
+		if err := article.WriteFrontmatter(); err != nil {
+			log.Printf("Warning: Failed to stamp %s: %v", article.ID, err)
+			continue
+		}
+		log.Printf("📅 Published: %s → %s", article.Title, article.PublicationURL)
+	}
+}
+
+// loadPublishedArticles loads all articles from the published directory
+func (h *HugoBuilder) loadPublishedArticles(publishedDir string) ([]*common.Article, error) {
+	articles, err := common.LoadArticles(publishedDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read published directory: %w", err)
+	}
 	return articles, nil
 }
@@ -0,0 +1,314 @@
+package builder
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func TestArticleFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{
+			BasePath:       filepath.Join(tmpDir, "NorseTinge"),
+			FolderLanguage: "da",
+		},
+		Hugo: config.HugoConfig{
+			SiteDir: filepath.Join(tmpDir, "site"),
+		},
+	}
+
+	h := NewHugoBuilder(cfg)
+
+	article := &common.Article{
+		ID:       "#ABC123",
+		Title:    "DevOps som paradigme",
+		Author:   "TB",
+		Language: "da",
+		Content:  "Dansk indhold",
+
+		PublicationDate: time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC),
+	}
+
+	// Store an English translation where the translator would put it
+	translation := *article
+	translation.Title = "DevOps as a \"paradigm\""
+	translation.Language = "en"
+	translation.Content = "English content"
+	translation.FilePath = h.translator.TranslationPath(article, "en")
+	os.MkdirAll(filepath.Dir(translation.FilePath), 0755)
+	if err := translation.WriteFrontmatter(); err != nil {
+		t.Fatalf("Failed to write translation: %v", err)
+	}
+
+	files, err := h.articleFiles(article)
+	if err != nil {
+		t.Fatalf("articleFiles failed: %v", err)
+	}
+
+	daFile, ok := files["abc123/index.da.md"]
+	if !ok {
+		t.Fatalf("Danish source not rendered: %v", files.paths())
+	}
+	if !strings.Contains(string(daFile), "url: /artikel/da/devops-som-paradigme/") {
+		t.Errorf("Danish page has wrong url:\n%s", daFile)
+	}
+	if !strings.Contains(string(daFile), "translationKey: abc123") {
+		t.Errorf("Danish page missing translationKey:\n%s", daFile)
+	}
+	if !strings.Contains(string(daFile), "date: 2025-10-03T12:00:00Z") {
+		t.Errorf("Danish page missing publication date:\n%s", daFile)
+	}
+
+	enFile, ok := files["abc123/index.en.md"]
+	if !ok {
+		t.Fatalf("English translation not rendered: %v", files.paths())
+	}
+
+	// Translations share the source slug and English gets the short alias
+	for _, want := range []string{
+		"url: /artikel/en/devops-som-paradigme/",
+		"- /artikel/devops-som-paradigme/",
+		"English content",
+		`title: DevOps as a "paradigm"`,
+	} {
+		if !strings.Contains(string(enFile), want) {
+			t.Errorf("English page missing %q:\n%s", want, enFile)
+		}
+	}
+}
+
+func TestSplitScheduled(t *testing.T) {
+	now := time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC)
+
+	articles := []*common.Article{
+		{ID: "#NOW001"},
+		{ID: "#PST001", PublishAt: now.Add(-time.Minute)},
+		{ID: "#FUT001", PublishAt: now.Add(time.Hour)},
+	}
+
+	ready, scheduled := splitScheduled(articles, now)
+	if len(ready) != 2 || ready[0].ID != "#NOW001" || ready[1].ID != "#PST001" {
+		t.Errorf("Unexpected ready articles: %v", ready)
+	}
+	if len(scheduled) != 1 || scheduled[0].ID != "#FUT001" {
+		t.Errorf("Unexpected scheduled articles: %v", scheduled)
+	}
+
+	// Scheduled articles are dated by publish_at until they are stamped
+	h := NewHugoBuilder(&config.Config{})
+	if page := h.newPublishedPage(articles[2], articles[2], "da"); !page.Date.Equal(articles[2].PublishAt) {
+		t.Errorf("Expected page date %v, got %v", articles[2].PublishAt, page.Date)
+	}
+}
+
+func TestStampPublished(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir},
+		Hugo:    config.HugoConfig{BaseURL: "https://norsetinge.com/"},
+	}
+	h := NewHugoBuilder(cfg)
+
+	write := func(name, frontmatter string) *common.Article {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, []byte("---\n"+frontmatter+"status:\n  published: 1\n---\n\nBody\n"), 0644)
+		article, err := common.ParseArticle(path)
+		if err != nil {
+			t.Fatalf("ParseArticle failed: %v", err)
+		}
+		return article
+	}
+
+	publishAt := time.Date(2025, 10, 3, 8, 0, 0, 0, time.UTC)
+	live := write("live.md", "id: \"#STP001\"\ntitle: Live nu\nauthor: TB\n")
+	scheduled := write("scheduled.md", "id: \"#STP002\"\ntitle: Planlagt\nauthor: TB\npublish_at: 2025-10-03T08:00:00Z\n")
+	old := write("old.md", "id: \"#STP003\"\ntitle: Gammel\nauthor: TB\npublication_date: 2025-01-02T10:00:00Z\npublication_url: https://norsetinge.com/artikel/da/gammel/\n")
+
+	before := time.Now().Add(-time.Second)
+	h.stampPublished([]*common.Article{live, scheduled, old})
+
+	stamped, _ := common.ParseArticle(live.FilePath)
+	if stamped.PublicationDate.Before(before) {
+		t.Errorf("Expected fresh publication_date, got %v", stamped.PublicationDate)
+	}
+	if stamped.PublicationURL != "https://norsetinge.com/artikel/da/live-nu/" {
+		t.Errorf("Unexpected publication_url: %s", stamped.PublicationURL)
+	}
+
+	// The page is rendered with the stamped date, not a new one per build
+	if page := h.newPublishedPage(live, live, "da"); !page.Date.Equal(stamped.PublicationDate) {
+		t.Errorf("Expected page date %v, got %v", stamped.PublicationDate, page.Date)
+	}
+
+	stamped, _ = common.ParseArticle(scheduled.FilePath)
+	if !stamped.PublicationDate.Equal(publishAt) {
+		t.Errorf("Expected scheduled article dated by publish_at, got %v", stamped.PublicationDate)
+	}
+
+	stamped, _ = common.ParseArticle(old.FilePath)
+	if !stamped.PublicationDate.Equal(time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Existing publication_date was overwritten: %v", stamped.PublicationDate)
+	}
+}
+
+func TestTombstoneFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{
+			BasePath:       filepath.Join(tmpDir, "NorseTinge"),
+			FolderLanguage: "da",
+		},
+		Hugo: config.HugoConfig{
+			SiteDir: filepath.Join(tmpDir, "site"),
+		},
+	}
+
+	h := NewHugoBuilder(cfg)
+
+	article := &common.Article{
+		ID:       "#TMB001",
+		Title:    "Trukket tilbage",
+		Author:   "TB",
+		Language: "da",
+		Content:  "Hemmeligt indhold",
+	}
+
+	translation := *article
+	translation.Language = "en"
+	translation.Content = "Secret content"
+	translation.FilePath = h.translator.TranslationPath(article, "en")
+	os.MkdirAll(filepath.Dir(translation.FilePath), 0755)
+	translation.WriteFrontmatter()
+
+	imagesDir := filepath.Join(cfg.Hugo.SiteDir, "static", "images", "tmb001")
+	os.MkdirAll(imagesDir, 0755)
+	os.WriteFile(filepath.Join(imagesDir, "photo-og.jpg"), []byte("jpeg"), 0644)
+
+	files, err := h.tombstoneFiles(article)
+	if err != nil {
+		t.Fatalf("tombstoneFiles failed: %v", err)
+	}
+
+	for lang, want := range map[string]string{
+		"da": "url: /artikel/da/trukket-tilbage/",
+		"en": "- /artikel/trukket-tilbage/",
+	} {
+		data, ok := files["tmb001/index."+lang+".md"]
+		if !ok {
+			t.Fatalf("Tombstone %s not rendered: %v", lang, files.paths())
+		}
+		page := string(data)
+		if !strings.Contains(page, want) || !strings.Contains(page, "layout: tombstone") || !strings.Contains(page, "list: never") {
+			t.Errorf("Unexpected %s tombstone:\n%s", lang, page)
+		}
+		if strings.Contains(page, "Hemmeligt") || strings.Contains(page, "Secret") {
+			t.Errorf("Tombstone %s still carries the article body:\n%s", lang, page)
+		}
+	}
+
+	if _, err := os.Stat(imagesDir); !os.IsNotExist(err) {
+		t.Error("Generated images were not removed")
+	}
+}
+
+func TestBuildPreviewIsIsolated(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir, FolderLanguage: "da"},
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "site", "public"),
+		},
+		Images: config.ImagesConfig{
+			Formats: []string{"jpeg"},
+			Sizes:   map[string][2]int{"og": {120, 63}},
+		},
+	}
+	h := NewHugoBuilder(cfg)
+
+	if want := filepath.Join(tmpDir, "previews"); h.PreviewDir() != want {
+		t.Errorf("Expected default preview dir %s, got %s", want, h.PreviewDir())
+	}
+
+	photo, _ := os.Create(filepath.Join(tmpDir, "photo.png"))
+	png.Encode(photo, image.NewRGBA(image.Rect(0, 0, 240, 126)))
+	photo.Close()
+
+	articlePath := filepath.Join(tmpDir, "preview.md")
+	source := "---\nid: \"#PRV001\"\ntitle: Preview Test\nauthor: TB\nimages: [photo.png]\n---\n\nBody\n"
+	os.WriteFile(articlePath, []byte(source), 0644)
+	article, err := common.ParseArticle(articlePath)
+	if err != nil {
+		t.Fatalf("ParseArticle failed: %v", err)
+	}
+
+	// hugo is not installed in tests - the build fails after the content is written
+	h.BuildPreview(article)
+
+	workspace := h.PreviewWorkspace(article)
+	content, err := os.ReadFile(filepath.Join(workspace, "content", "preview-test.md"))
+	if err != nil {
+		t.Errorf("Preview content not written to the workspace: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(cfg.Hugo.SiteDir, "content")); len(entries) != 0 {
+		t.Errorf("Preview wrote into the site content directory: %v", entries)
+	}
+
+	// Images are generated into the preview and linked below its URL
+	if _, err := os.Stat(filepath.Join(workspace, "public", "images", "prv001", "photo-og.jpg")); err != nil {
+		t.Errorf("Preview image not generated in the workspace: %v", err)
+	}
+	if !strings.Contains(string(content), "/preview/prv001/images/prv001/photo-og.jpg") {
+		t.Errorf("Preview page does not link its own image:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Hugo.SiteDir, "static")); !os.IsNotExist(err) {
+		t.Errorf("Preview wrote into the site static directory: %v", err)
+	}
+	if data, _ := os.ReadFile(articlePath); string(data) != source {
+		t.Errorf("Preview rewrote the article file:\n%s", data)
+	}
+}
+
+func TestRemoveLegacyPreviews(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		Hugo: config.HugoConfig{
+			SiteDir:   filepath.Join(tmpDir, "site"),
+			PublicDir: filepath.Join(tmpDir, "public"),
+		},
+	}
+
+	legacy := []string{
+		filepath.Join(cfg.Hugo.SiteDir, "content", "preview-old.md"),
+		filepath.Join(cfg.Hugo.PublicDir, "preview-old", "index.html"),
+	}
+	kept := filepath.Join(cfg.Hugo.PublicDir, "artikel", "index.html")
+	for _, path := range append(legacy, kept) {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+
+	NewHugoBuilder(cfg).removeLegacyPreviews()
+
+	for _, path := range legacy {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Old preview %s not removed", path)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("Site page removed: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"norsetinge/src/config"
+)
+
+// DeployLock is an exclusive flock(2) on a lock file, held while the site is built and
+// deployed. It keeps processes apart - the server and a CLI rollback, or two servers -
+// so they never write public/, the mirror or the webhost at the same time. The kernel
+// drops the lock when the holder dies, so a crash never leaves a stale lock behind;
+// the file only records the PID of the last holder.
+// A DeployLock is not safe for concurrent use; the Scheduler holds it under its mutex.
+type DeployLock struct {
+	path string
+	file *os.File
+}
+
+// NewDeployLock creates the lock for build.lock_file, by default .deploy.lock in the site directory
+func NewDeployLock(cfg *config.Config) *DeployLock {
+	path := cfg.Build.LockFile
+	if path == "" {
+		path = filepath.Join(cfg.Hugo.SiteDir, ".deploy.lock")
+	}
+	return &DeployLock{path: path}
+}
+
+// Path returns the lock file
+func (l *DeployLock) Path() string {
+	return l.path
+}
+
+// Lock takes the lock, waiting while another process holds it
+func (l *DeployLock) Lock() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open deploy lock: %w", err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		log.Printf("⏳ Waiting for deploy lock %s (held by PID %s)...", l.path, lockHolder(file))
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to take deploy lock: %w", err)
+	}
+
+	// Record the holder for the next one waiting
+	if file.Truncate(0) == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	l.file = file
+	return nil
+}
+
+// Unlock releases the lock. The file is kept; removing it would let a process that
+// already opened it lock a different inode than the next one.
+func (l *DeployLock) Unlock() {
+	if l.file == nil {
+		return
+	}
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+}
+
+// lockHolder returns the PID recorded in the lock file, or "?"
+func lockHolder(file *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	if pid := strings.TrimSpace(string(buf[:n])); pid != "" {
+		return pid
+	}
+	return "?"
+}
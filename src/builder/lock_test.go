@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/config"
+)
+
+func TestDeployLock(t *testing.T) {
+	cfg := &config.Config{Hugo: config.HugoConfig{SiteDir: filepath.Join(t.TempDir(), "site")}}
+
+	first := NewDeployLock(cfg)
+	if err := first.Lock(); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if data, _ := os.ReadFile(first.Path()); strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the holder's PID in the lock file, got %q", data)
+	}
+
+	// A second lock on the same file (flock is per open file, like another process) waits
+	second := NewDeployLock(cfg)
+	acquired := make(chan error)
+	go func() { acquired <- second.Lock() }()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second lock acquired while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Unlock()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("Second lock failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Second lock not acquired after unlock")
+	}
+	second.Unlock()
+	second.Unlock() // Harmless when not held
+}
@@ -0,0 +1,206 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BuildResult is the outcome of a full site build
+type BuildResult struct {
+	PublicDir string
+	MirrorDir string
+
+	// Article IDs by what happened to their content files
+	Added     []string
+	Changed   []string
+	Removed   []string
+	Unchanged int
+}
+
+// Summary returns the content changes for log messages
+func (r *BuildResult) Summary() string {
+	return fmt.Sprintf("%d added, %d changed, %d removed, %d unchanged", len(r.Added), len(r.Changed), len(r.Removed), r.Unchanged)
+}
+
+// contentFiles are the Hugo content files of one article, by path relative to content/articles
+type contentFiles map[string][]byte
+
+// hash returns a hash over all paths and contents
+func (f contentFiles) hash() string {
+	hash := sha256.New()
+	for _, path := range f.paths() {
+		fmt.Fprintf(hash, "%s\n%d\n", path, len(f[path]))
+		hash.Write(f[path])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// paths returns the file paths in sorted order
+func (f contentFiles) paths() []string {
+	paths := make([]string, 0, len(f))
+	for path := range f {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// manifestEntry is what the last build wrote for one article
+type manifestEntry struct {
+	Hash  string   `json:"hash"`
+	Files []string `json:"files"`
+}
+
+// buildManifest maps article ID to the content files written for it
+type buildManifest map[string]manifestEntry
+
+// loadManifest reads the manifest; ok is false if there is none (or it is unreadable)
+func loadManifest(path string) (manifest buildManifest, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to read build manifest: %v", err)
+		}
+		return buildManifest{}, false
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Printf("Warning: Failed to parse build manifest, rewriting all content: %v", err)
+		return buildManifest{}, false
+	}
+	return manifest, true
+}
+
+// save writes the manifest atomically
+func (m buildManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build manifest: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write build manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// syncContent makes contentDir hold exactly the wanted files. Articles whose hash matches
+// the manifest are left alone, and only files whose content differs are written, so
+// unchanged files keep their mtime. Files of removed articles, and any file no article
+// claims, are deleted. Returns the new manifest and fills in the changes in result.
+func syncContent(contentDir string, previous buildManifest, wanted map[string]contentFiles, result *BuildResult) (buildManifest, error) {
+	manifest := buildManifest{}
+	claimed := make(map[string]bool)
+
+	ids := make([]string, 0, len(wanted))
+	for id := range wanted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		files := wanted[id]
+		entry := manifestEntry{Hash: files.hash(), Files: files.paths()}
+		manifest[id] = entry
+		for _, path := range entry.Files {
+			claimed[path] = true
+		}
+
+		old, existed := previous[id]
+		if existed && old.Hash == entry.Hash && filesExist(contentDir, entry.Files) {
+			result.Unchanged++
+			continue
+		}
+
+		for _, path := range entry.Files {
+			if err := writeIfChanged(filepath.Join(contentDir, path), files[path]); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+
+		if existed {
+			result.Changed = append(result.Changed, id)
+			log.Printf("  ✓ Updated: %s (%d files)", id, len(entry.Files))
+		} else {
+			result.Added = append(result.Added, id)
+			log.Printf("  ✓ Added: %s (%d files)", id, len(entry.Files))
+		}
+	}
+
+	for id := range previous {
+		if _, ok := wanted[id]; !ok {
+			result.Removed = append(result.Removed, id)
+			log.Printf("  🗑️ Removed: %s", id)
+		}
+	}
+	sort.Strings(result.Removed)
+
+	// Delete everything not claimed: files of removed articles, languages dropped from
+	// an article, and leftovers from before the manifest existed
+	err := filepath.Walk(contentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(contentDir, path)
+		if err != nil || info.IsDir() || claimed[filepath.ToSlash(rel)] {
+			return err
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove stale content: %w", err)
+	}
+
+	removeEmptyDirs(contentDir)
+	return manifest, nil
+}
+
+// writeIfChanged writes data to path unless the file already holds exactly that
+func writeIfChanged(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// filesExist reports whether all files are present in dir
+func filesExist(dir string, files []string) bool {
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// removeEmptyDirs removes the empty subdirectories of dir (bundles of removed articles)
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		sub := filepath.Join(dir, entry.Name())
+		removeEmptyDirs(sub)
+		os.Remove(sub) // Fails, harmlessly, unless empty
+	}
+}
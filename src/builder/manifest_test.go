@@ -0,0 +1,96 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncContent(t *testing.T) {
+	contentDir := t.TempDir()
+
+	wanted := map[string]contentFiles{
+		"#AAA001": {"aaa001/index.da.md": []byte("a da"), "aaa001/index.en.md": []byte("a en")},
+		"#BBB002": {"bbb002/index.da.md": []byte("b da")},
+	}
+
+	// Leftover from a build before the manifest existed
+	os.MkdirAll(filepath.Join(contentDir, "old"), 0755)
+	os.WriteFile(filepath.Join(contentDir, "old", "index.da.md"), []byte("old"), 0644)
+
+	result := &BuildResult{}
+	manifest, err := syncContent(contentDir, buildManifest{}, wanted, result)
+	if err != nil {
+		t.Fatalf("syncContent failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"#AAA001", "#BBB002"}) || len(result.Changed) != 0 || len(result.Removed) != 0 {
+		t.Errorf("First build: unexpected result %s", result.Summary())
+	}
+	if _, err := os.Stat(filepath.Join(contentDir, "old")); !os.IsNotExist(err) {
+		t.Error("Unclaimed leftover was not removed")
+	}
+
+	// Backdate so a rewrite would be visible in the mtime
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, path := range []string{"aaa001/index.da.md", "aaa001/index.en.md", "bbb002/index.da.md"} {
+		os.Chtimes(filepath.Join(contentDir, path), past, past)
+	}
+
+	// Second build: one language of A changed, B removed, C added
+	wanted = map[string]contentFiles{
+		"#AAA001": {"aaa001/index.da.md": []byte("a da"), "aaa001/index.en.md": []byte("a en v2")},
+		"#CCC003": {"ccc003/index.da.md": []byte("c da")},
+	}
+	result = &BuildResult{}
+	if _, err := syncContent(contentDir, manifest, wanted, result); err != nil {
+		t.Fatalf("syncContent failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Added, []string{"#CCC003"}) ||
+		!reflect.DeepEqual(result.Changed, []string{"#AAA001"}) ||
+		!reflect.DeepEqual(result.Removed, []string{"#BBB002"}) {
+		t.Errorf("Second build: unexpected result %+v", result)
+	}
+
+	if info, _ := os.Stat(filepath.Join(contentDir, "aaa001", "index.da.md")); !info.ModTime().Equal(past) {
+		t.Error("Unchanged file in a changed article was rewritten")
+	}
+	if data, _ := os.ReadFile(filepath.Join(contentDir, "aaa001", "index.en.md")); string(data) != "a en v2" {
+		t.Errorf("Changed file not written, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(contentDir, "bbb002")); !os.IsNotExist(err) {
+		t.Error("Removed article's bundle still exists")
+	}
+}
+
+func TestSyncContentSkipsUnchanged(t *testing.T) {
+	contentDir := t.TempDir()
+	wanted := map[string]contentFiles{"#AAA001": {"aaa001/index.da.md": []byte("a da")}}
+
+	manifest, _ := syncContent(contentDir, buildManifest{}, wanted, &BuildResult{})
+	manifestPath := filepath.Join(t.TempDir(), ".build-manifest.json")
+	if err := manifest.save(manifestPath); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, ok := loadManifest(manifestPath)
+	if !ok || !reflect.DeepEqual(loaded, manifest) {
+		t.Fatalf("Manifest did not round-trip: %+v", loaded)
+	}
+
+	result := &BuildResult{}
+	syncContent(contentDir, loaded, wanted, result)
+	if result.Unchanged != 1 || len(result.Added)+len(result.Changed)+len(result.Removed) != 0 {
+		t.Errorf("Expected the article to be unchanged, got %s", result.Summary())
+	}
+
+	// A file deleted behind the builder's back is written again
+	os.Remove(filepath.Join(contentDir, "aaa001", "index.da.md"))
+	result = &BuildResult{}
+	syncContent(contentDir, loaded, wanted, result)
+	if len(result.Changed) != 1 {
+		t.Errorf("Expected the missing file to be rewritten, got %s", result.Summary())
+	}
+}
@@ -0,0 +1,416 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+	"norsetinge/src/translator"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultBuildInterval is how often the scheduler checks for changes when build.interval is not set
+	defaultBuildInterval = 10 * time.Minute
+
+	// defaultBuildDebounce is how long the scheduler waits for more changes when build.debounce is not set
+	defaultBuildDebounce = 30 * time.Second
+)
+
+// Scheduler runs the site build+deploy when its inputs change: after content changes
+// settle (debounce), and on a ticker as a fallback. It is the one coordinator for builds
+// and deploys: runs never overlap, in this process (mu) or with other processes (the
+// deploy lock file), requests arriving while a build runs share one follow-up build,
+// and a run is skipped when the inputs hash matches the last successful build.
+type Scheduler struct {
+	cfg      *config.Config
+	run      func() error
+	interval time.Duration
+	debounce time.Duration
+	changes  chan struct{}
+	lock     *DeployLock
+
+	mu        sync.Mutex // Held for the whole run - one build at a time
+	lastHash  string     // Inputs of the last successful run
+	lastStart time.Time  // Start of the last successful run
+
+	stateMu    sync.Mutex // Guards running, queued and undeployed, so Status never waits for a build
+	running    *RunStatus
+	queued     *queuedRun
+	undeployed time.Time // End of a build without deploy, until the next deploy
+}
+
+// RunStatus describes a running or queued build+deploy
+type RunStatus struct {
+	Reason  string    `json:"reason"`
+	Since   time.Time `json:"since"`             // Started, or first requested if queued
+	Waiting int       `json:"waiting,omitempty"` // Requests sharing the queued run
+}
+
+// SchedulerStatus is what the scheduler is doing: the current run and the queued one
+type SchedulerStatus struct {
+	Running    *RunStatus `json:"running,omitempty"`
+	Queued     *RunStatus `json:"queued,omitempty"`
+	Undeployed *time.Time `json:"undeployed,omitempty"` // public/ was built without a deploy at this time
+}
+
+// queuedRun is the follow-up build that every BuildNow during a run waits for
+type queuedRun struct {
+	status RunStatus
+	done   chan struct{}
+	err    error
+}
+
+// NewScheduler creates a scheduler that calls run to build and deploy the site
+func NewScheduler(cfg *config.Config, run func() error) *Scheduler {
+	interval := cfg.Build.Interval
+	if interval <= 0 {
+		interval = defaultBuildInterval
+	}
+	debounce := cfg.Build.Debounce
+	if debounce <= 0 {
+		debounce = defaultBuildDebounce
+	}
+
+	return &Scheduler{
+		cfg:      cfg,
+		run:      run,
+		interval: interval,
+		debounce: debounce,
+		changes:  make(chan struct{}, 1),
+		lock:     NewDeployLock(cfg),
+	}
+}
+
+// Start runs the scheduler loop until stop is closed
+func (s *Scheduler) Start(stop <-chan struct{}) {
+	log.Printf("🔨 Build scheduler started (interval %s, debounce %s)", s.interval, s.debounce)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	debounce := time.NewTimer(s.debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-s.changes:
+			// Every change restarts the wait, so a burst of saves gives one build
+			debounce.Reset(s.debounce)
+		case <-debounce.C:
+			s.runIfChanged("content changed")
+		case <-ticker.C:
+			s.runIfChanged("periodic")
+		}
+	}
+}
+
+// Notify tells the scheduler a file changed. Only site inputs trigger a build.
+func (s *Scheduler) Notify(path string) {
+	if !s.isInput(path) {
+		return
+	}
+
+	select {
+	case s.changes <- struct{}{}:
+	default: // A change is already waiting
+	}
+}
+
+// BuildNow builds and deploys immediately, waiting for a running build first.
+// Requests made while a build runs are queued as one follow-up build and all get its
+// result. If a build started after this call and succeeded it already covers the request.
+func (s *Scheduler) BuildNow() error {
+	requested := time.Now()
+
+	s.stateMu.Lock()
+	if queued := s.queued; queued != nil {
+		queued.status.Waiting++
+		s.stateMu.Unlock()
+		log.Printf("🔨 Build already queued - joining it")
+		<-queued.done
+		return queued.err
+	}
+	queued := &queuedRun{status: RunStatus{Reason: "requested", Since: requested, Waiting: 1}, done: make(chan struct{})}
+	s.queued = queued
+	s.stateMu.Unlock()
+
+	var err error
+	defer func() {
+		queued.err = err
+		close(queued.done)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// From here on, new requests queue behind this run
+	s.stateMu.Lock()
+	s.queued = nil
+	s.stateMu.Unlock()
+
+	if s.lastStart.After(requested) {
+		log.Printf("🔨 Build requested during a build - already included")
+		return nil
+	}
+
+	hash, hashErr := s.InputsHash(time.Now())
+	if hashErr != nil {
+		log.Printf("Warning: Failed to hash build inputs: %v", hashErr)
+	}
+	err = s.runLocked("requested", hash)
+	return err
+}
+
+// Build runs build, a build without deploy, like a scheduled run (one at a time, under
+// the deploy lock) and marks public/ as undeployed: the next scheduled run deploys it
+// even if the inputs are unchanged
+func (s *Scheduler) Build(build func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setRunning(&RunStatus{Reason: "build", Since: time.Now()})
+	defer s.setRunning(nil)
+
+	if err := s.lock.Lock(); err != nil {
+		return err
+	}
+	defer s.lock.Unlock()
+
+	// Even a failed build may have rewritten part of public/
+	err := build()
+	s.stateMu.Lock()
+	s.undeployed = time.Now()
+	s.stateMu.Unlock()
+	return err
+}
+
+// Exclusive runs fn while no build runs, holding the deploy lock, e.g. a rollback
+// that deploys an older site
+func (s *Scheduler) Exclusive(reason string, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setRunning(&RunStatus{Reason: reason, Since: time.Now()})
+	defer s.setRunning(nil)
+
+	if err := s.lock.Lock(); err != nil {
+		return err
+	}
+	defer s.lock.Unlock()
+	return fn()
+}
+
+// Status returns the running and queued builds
+func (s *Scheduler) Status() SchedulerStatus {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	var status SchedulerStatus
+	if s.running != nil {
+		running := *s.running
+		status.Running = &running
+	}
+	if s.queued != nil {
+		queued := s.queued.status
+		status.Queued = &queued
+	}
+	if !s.undeployed.IsZero() {
+		undeployed := s.undeployed
+		status.Undeployed = &undeployed
+	}
+	return status
+}
+
+// setRunning records the run in progress (nil when idle)
+func (s *Scheduler) setRunning(run *RunStatus) {
+	s.stateMu.Lock()
+	s.running = run
+	s.stateMu.Unlock()
+}
+
+// runIfChanged builds and deploys unless the inputs are the same as in the last successful run
+func (s *Scheduler) runIfChanged(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateMu.Lock()
+	undeployed := !s.undeployed.IsZero()
+	s.stateMu.Unlock()
+
+	hash, err := s.InputsHash(time.Now())
+	if err != nil {
+		log.Printf("Warning: Failed to hash build inputs: %v", err)
+	} else if hash == s.lastHash && !undeployed {
+		log.Printf("⏭️  Skipping build (%s): inputs unchanged", reason)
+		return
+	} else if hash == s.lastHash {
+		reason += ", undeployed build in public/"
+	}
+
+	log.Printf("⏰ Running build+deploy (%s)...", reason)
+	if err := s.runLocked(reason, hash); err != nil {
+		log.Printf("Error in build+deploy: %v", err)
+		return
+	}
+	log.Printf("✅ Build+deploy completed")
+}
+
+// runLocked runs the build under the deploy lock. If it succeeded it remembers its
+// inputs, and public/ is deployed. s.mu must be held.
+func (s *Scheduler) runLocked(reason, hash string) error {
+	start := time.Now()
+	s.setRunning(&RunStatus{Reason: reason, Since: start})
+	defer s.setRunning(nil)
+
+	if err := s.lock.Lock(); err != nil {
+		return err
+	}
+	defer s.lock.Unlock()
+
+	if err := s.run(); err != nil {
+		return err
+	}
+
+	s.lastHash = hash
+	s.lastStart = start
+	s.stateMu.Lock()
+	s.undeployed = time.Time{}
+	s.stateMu.Unlock()
+	return nil
+}
+
+// inputPaths returns the folders and files the site is built from
+func (s *Scheduler) inputPaths() []string {
+	site := s.cfg.Hugo.SiteDir
+	return []string{
+		s.cfg.StatusDir("published"),
+		s.cfg.StatusDir("unpublish"),
+		translator.NewTranslator(s.cfg).TranslationsDir(),
+		filepath.Join(site, "layouts"),
+		filepath.Join(site, "assets"),
+		filepath.Join(site, "i18n"),
+		filepath.Join(site, "data"),
+		filepath.Join(site, "hugo.toml"),
+	}
+}
+
+// isInput reports whether path is in one of the input paths
+func (s *Scheduler) isInput(path string) bool {
+	for _, input := range s.inputPaths() {
+		if rel, err := filepath.Rel(input, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// InputsHash hashes everything a build depends on at now: the content of all input files
+// and which published articles are still waiting for their publish_at
+func (s *Scheduler) InputsHash(now time.Time) (string, error) {
+	hash := sha256.New()
+
+	for _, input := range s.inputPaths() {
+		var files []string
+		err := filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to walk %s: %w", input, err)
+		}
+
+		sort.Strings(files)
+		for _, path := range files {
+			hashInput := hashFile
+			if strings.HasSuffix(path, ".md") {
+				hashInput = hashArticle
+			}
+			if err := hashInput(hash, path); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// A scheduled article changes the site when its publish_at passes, without any file change
+	published, err := common.LoadArticles(s.cfg.StatusDir("published"))
+	if err != nil {
+		return "", err
+	}
+	_, scheduled := splitScheduled(published, now)
+	for _, article := range scheduled {
+		fmt.Fprintf(hash, "scheduled %s\n", article.ID)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashArticle hashes an article as the build reads it, without the fields the build
+// writes back itself (publication stamp, processed images and icons). Writing those, or
+// reformatting the front matter while doing so, must not trigger another build.
+// An article that does not parse is hashed as it is.
+func hashArticle(hash io.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	fmt.Fprintf(hash, "%s\n", path)
+
+	var article common.Article
+	parts := bytes.SplitN(data, []byte("---"), 3)
+	if len(parts) < 3 || yaml.Unmarshal(parts[1], &article) != nil {
+		hash.Write(data)
+		return nil
+	}
+	article.Content = string(bytes.TrimSpace(parts[2]))
+	article.PublicationDate = time.Time{}
+	article.PublicationURL = ""
+	article.ProcessedImages = nil
+	article.ProcessedIcons = nil
+
+	markdown, err := article.Markdown()
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	hash.Write(markdown)
+	return nil
+}
+
+// hashFile adds a file's path and content to hash
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	fmt.Fprintf(hash, "%s\n", path)
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+func newTestScheduler(t *testing.T, run func() error) (*Scheduler, string) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{BasePath: tmpDir, FolderLanguage: "da"},
+		Hugo:    config.HugoConfig{SiteDir: filepath.Join(tmpDir, "site")},
+		Build:   config.BuildConfig{Interval: time.Hour, Debounce: 50 * time.Millisecond},
+	}
+	os.MkdirAll(filepath.Join(tmpDir, "udgivet"), 0755)
+	return NewScheduler(cfg, run), tmpDir
+}
+
+func writeArticle(t *testing.T, path, extra string) {
+	t.Helper()
+	content := "---\nid: \"#SCH001\"\ntitle: Test\nauthor: TB\n" + extra + "---\n\nBody\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write article: %v", err)
+	}
+}
+
+func TestSchedulerDebouncesAndSkipsUnchanged(t *testing.T) {
+	var runs atomic.Int32
+	s, tmpDir := newTestScheduler(t, func() error {
+		runs.Add(1)
+		return nil
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Start(stop)
+
+	// A burst of changes gives one build
+	article := filepath.Join(tmpDir, "udgivet", "test.md")
+	writeArticle(t, article, "")
+	for i := 0; i < 5; i++ {
+		s.Notify(article)
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("Expected 1 build after a burst of changes, got %d", got)
+	}
+
+	// Same inputs - skipped
+	s.Notify(article)
+	time.Sleep(200 * time.Millisecond)
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected unchanged inputs to be skipped, got %d builds", got)
+	}
+
+	// Files outside the site inputs are ignored
+	draft := filepath.Join(tmpDir, "kladde", "draft.md")
+	os.MkdirAll(filepath.Dir(draft), 0755)
+	writeArticle(t, draft, "")
+	s.Notify(draft)
+	time.Sleep(200 * time.Millisecond)
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected change outside udgivet/ to be ignored, got %d builds", got)
+	}
+}
+
+func TestSchedulerBuildNowNeverOverlaps(t *testing.T) {
+	var running, overlaps, runs atomic.Int32
+	s, _ := newTestScheduler(t, func() error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		runs.Add(1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.BuildNow(); err != nil {
+				t.Errorf("BuildNow failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlaps.Load() != 0 {
+		t.Errorf("Builds overlapped %d times", overlaps.Load())
+	}
+	// Requests waiting on the first build are coalesced into at most one more
+	if got := runs.Load(); got < 1 || got > 2 {
+		t.Errorf("Expected 1-2 builds for 5 concurrent requests, got %d", got)
+	}
+}
+
+func TestSchedulerQueuesRequestsDuringRun(t *testing.T) {
+	var runs atomic.Int32
+	release := make(chan struct{})
+	s, _ := newTestScheduler(t, func() error {
+		if runs.Add(1) == 1 {
+			<-release
+		}
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.BuildNow()
+	}()
+	waitFor(t, func() bool { return s.Status().Running != nil })
+
+	// Three requests during the run share one queued follow-up
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.BuildNow(); err != nil {
+				t.Errorf("BuildNow failed: %v", err)
+			}
+		}()
+	}
+	waitFor(t, func() bool { q := s.Status().Queued; return q != nil && q.Waiting == 3 })
+
+	status := s.Status()
+	if status.Running.Reason != "requested" {
+		t.Errorf("Unexpected running status: %+v", status.Running)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the queued requests to share one build, got %d builds", got)
+	}
+	if status := s.Status(); status.Running != nil || status.Queued != nil {
+		t.Errorf("Expected an idle scheduler, got %+v", status)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("Condition not met in time")
+}
+
+func TestInputsHash(t *testing.T) {
+	s, tmpDir := newTestScheduler(t, func() error { return nil })
+	article := filepath.Join(tmpDir, "udgivet", "test.md")
+
+	now := time.Now()
+	writeArticle(t, article, "publish_at: "+now.Add(time.Hour).Format(time.RFC3339)+"\n")
+	before, err := s.InputsHash(now)
+	if err != nil {
+		t.Fatalf("InputsHash failed: %v", err)
+	}
+
+	if same, _ := s.InputsHash(now); same != before {
+		t.Error("Hash changed without any change")
+	}
+
+	// publish_at passing changes the site without a file change
+	if after, _ := s.InputsHash(now.Add(2 * time.Hour)); after == before {
+		t.Error("Hash did not change when publish_at passed")
+	}
+
+	// The build's own publication stamp is not a change
+	writeArticle(t, article, "publish_at: "+now.Add(time.Hour).Format(time.RFC3339)+"\npublication_date: 2025-10-03T08:00:00Z\npublication_url: https://norsetinge.com/artikel/da/test/\n")
+	if stamped, _ := s.InputsHash(now); stamped != before {
+		t.Error("Hash changed when the article was stamped")
+	}
+
+	// ... and neither are the processed images and icons it writes back
+	writeArticle(t, article, "publish_at: "+now.Add(time.Hour).Format(time.RFC3339)+"\nprocessed_images:\n  - source: hero.jpg\n    sizes:\n      large:\n        jpeg: /images/hero-large.jpg\nprocessed_icons:\n  favicon_source: icon.png\n  app_icon_source: icon.png\n  favicon: /icons/favicon.ico\n")
+	if processed, _ := s.InputsHash(now); processed != before {
+		t.Error("Hash changed when processed images and icons were written")
+	}
+
+	writeArticle(t, article, "publish_at: "+now.Add(time.Hour).Format(time.RFC3339)+"\ndescription: changed\n")
+	if changed, _ := s.InputsHash(now); changed == before {
+		t.Error("Hash did not change when an article changed")
+	}
+}
+
+func TestSchedulerDoesNotRebuildAfterBuildWritesArticle(t *testing.T) {
+	var runs atomic.Int32
+	var article string
+
+	// Like BuildFullSite: stamps the article and writes its processed images back
+	s, tmpDir := newTestScheduler(t, func() error {
+		runs.Add(1)
+		parsed, err := common.ParseArticle(article)
+		if err != nil {
+			return err
+		}
+		parsed.PublicationDate = time.Date(2025, 10, 3, 8, 0, 0, 0, time.UTC)
+		parsed.PublicationURL = "https://norsetinge.com/artikel/da/test/"
+		parsed.ProcessedImages = []common.ProcessedImage{{Source: "hero.jpg", Sizes: map[string]map[string]string{"large": {"jpeg": "/images/hero-large.jpg"}}}}
+		parsed.ProcessedIcons = &common.IconSet{FaviconSource: "icon.png", Favicon: "/icons/favicon.ico"}
+		return parsed.WriteFrontmatter()
+	})
+	article = filepath.Join(tmpDir, "udgivet", "test.md")
+	writeArticle(t, article, "images: [hero.jpg]\nfavicon: icon.png\n")
+
+	s.runIfChanged("content changed")
+	s.runIfChanged("periodic")
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected 1 build, got %d - the build's own writes triggered another", got)
+	}
+}
+
+func TestSchedulerDeploysBuildWithoutDeploy(t *testing.T) {
+	var runs atomic.Int32
+	s, tmpDir := newTestScheduler(t, func() error {
+		runs.Add(1)
+		return nil
+	})
+	writeArticle(t, filepath.Join(tmpDir, "udgivet", "test.md"), "")
+
+	s.runIfChanged("content changed")
+	if err := s.Build(func() error { return nil }); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if s.Status().Undeployed == nil {
+		t.Fatal("Expected public/ to be marked undeployed after a build")
+	}
+
+	// Unchanged inputs, but public/ differs from what is live - deployed
+	s.runIfChanged("periodic")
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the undeployed build to be deployed, got %d runs", got)
+	}
+	if s.Status().Undeployed != nil {
+		t.Error("Expected undeployed to be cleared by the deploy")
+	}
+
+	s.runIfChanged("periodic")
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected unchanged inputs to be skipped after the deploy, got %d runs", got)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"norsetinge/src/common"
 	"norsetinge/src/config"
 	"os"
-	"path/filepath"
 )
 
 func main() {
@@ -19,7 +18,7 @@ func main() {
 	articlePath := os.Args[1]
 
 	// Load config
-	cfg, err := config.Load("../../../config.yaml")
+	cfg, err := config.Load("../../../config.yaml", "../../../folder-aliases.yaml")
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -41,8 +40,19 @@ func main() {
 	if err := server.RequestApproval(article); err != nil {
 		log.Fatalf("Approval failed: %v", err)
 	}
+	server.WaitForPreviews()
+	for _, job := range server.PreviewJobs() {
+		if job.ID == article.ID && job.State == approval.PreviewFailed {
+			log.Fatalf("Approval failed: %s", job.Err)
+		}
+	}
 
 	fmt.Println("✅ Approval request sent successfully!")
-	fmt.Printf("Check your email at: %s\n", cfg.Email.ApprovalRecipient)
-	fmt.Printf("Preview location: %s/godkendelse/%s/\n", filepath.Dir(cfg.Dropbox.BasePath), article.GetSlug())
+	if cfg.Email.Enabled {
+		fmt.Printf("Check your email at: %s\n", cfg.Email.ApprovalRecipient)
+	}
+	if cfg.Ntfy.Enabled {
+		fmt.Printf("Check ntfy topic: %s\n", cfg.Ntfy.Topic)
+	}
+	fmt.Printf("Approval page: %s\n", server.ApprovalURL(article.ID))
 }
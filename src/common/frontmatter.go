@@ -5,8 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -25,6 +29,9 @@ type Article struct {
 	Author string `yaml:"author"`
 	Status Status `yaml:"status"`
 
+	// Editor feedback from "suggest changes" on the approval page
+	EditorComments string `yaml:"editor_comments,omitempty"`
+
 	// Optional SEO fields
 	Description string   `yaml:"description,omitempty"`
 	Images      []string `yaml:"images,omitempty"`
@@ -32,6 +39,9 @@ type Article struct {
 	Videos      []string `yaml:"videos,omitempty"`
 	Audio       []string `yaml:"audio,omitempty"`
 
+	// Generated by the image processor (do not edit manually)
+	ProcessedImages []ProcessedImage `yaml:"processed_images,omitempty"`
+
 	// Optional organization fields
 	Slug       string   `yaml:"slug,omitempty"`
 	Categories []string `yaml:"categories,omitempty"`
@@ -41,11 +51,21 @@ type Article struct {
 	Favicon string `yaml:"favicon,omitempty"`
 	AppIcon string `yaml:"app_icon,omitempty"`
 
+	// Generated from Favicon/AppIcon by the image processor (do not edit manually)
+	ProcessedIcons *IconSet `yaml:"processed_icons,omitempty"`
+
 	// Optional language field (ISO 639-1 code, e.g., "da", "en", "de")
 	Language string `yaml:"language,omitempty"`
 
-	// Raw content (after frontmatter)
-	Content string
+	// Optional: do not publish before this time, even when approved
+	PublishAt time.Time `yaml:"publish_at,omitempty"`
+
+	// Set by the deployer when the article first goes live
+	PublicationDate time.Time `yaml:"publication_date,omitempty"`
+	PublicationURL  string    `yaml:"publication_url,omitempty"`
+
+	// Raw content (after frontmatter) - written after it, never as a frontmatter key
+	Content string `yaml:"-"`
 }
 
 // Status represents the article workflow status
@@ -56,6 +76,7 @@ type Status struct {
 	Published int `yaml:"published"`
 	Rejected  int `yaml:"rejected"`
 	Update    int `yaml:"update"`
+	Unpublish int `yaml:"unpublish"` // Take a published article down
 }
 
 // ParseArticle reads a markdown file and parses the YAML frontmatter
@@ -101,6 +122,36 @@ func ParseArticle(filePath string) (*Article, error) {
 	return article, nil
 }
 
+// LoadArticles parses all markdown articles in a directory.
+// Files that fail to parse are logged and skipped; a missing directory returns no articles.
+func LoadArticles(dir string) ([]*Article, error) {
+	var articles []*Article
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return articles, nil
+		}
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		article, err := ParseArticle(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("Warning: Failed to parse %s: %v", entry.Name(), err)
+			continue
+		}
+
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
+
 // GetCurrentStatus returns the current status based on "last 1 wins" rule
 func (a *Article) GetCurrentStatus() string {
 	statuses := []struct {
@@ -113,6 +164,7 @@ func (a *Article) GetCurrentStatus() string {
 		{"published", a.Status.Published},
 		{"rejected", a.Status.Rejected},
 		{"update", a.Status.Update},
+		{"unpublish", a.Status.Unpublish},
 	}
 
 	currentStatus := "unknown"
@@ -125,8 +177,12 @@ func (a *Article) GetCurrentStatus() string {
 	return currentStatus
 }
 
-// GetSlug returns a URL-friendly slug from the title
+// GetSlug returns the slug from frontmatter, or a URL-friendly slug from the title
 func (a *Article) GetSlug() string {
+	if a.Slug != "" {
+		return a.Slug
+	}
+
 	slug := strings.ToLower(a.Title)
 	slug = strings.ReplaceAll(slug, " ", "-")
 	slug = strings.ReplaceAll(slug, "æ", "ae")
@@ -137,6 +193,54 @@ func (a *Article) GetSlug() string {
 	return slug
 }
 
+// GetIDSlug returns the article ID without the leading '#', safe for file paths and URLs
+func (a *Article) GetIDSlug() string {
+	return strings.ToLower(strings.TrimPrefix(a.ID, "#"))
+}
+
+// GetURLPath returns the public URL path of the article in a language: /artikel/{lang}/{slug}/
+func (a *Article) GetURLPath(lang string) string {
+	return fmt.Sprintf("/artikel/%s/%s/", lang, a.GetSlug())
+}
+
+// IsScheduled reports whether publish_at is set and still in the future at now
+func (a *Article) IsScheduled(now time.Time) bool {
+	return !a.PublishAt.IsZero() && a.PublishAt.After(now)
+}
+
+// Clone returns a deep copy, so callers can change it without affecting the original
+func (a *Article) Clone() *Article {
+	if a == nil {
+		return nil
+	}
+
+	clone := *a
+	clone.Images = slices.Clone(a.Images)
+	clone.Tags = slices.Clone(a.Tags)
+	clone.Videos = slices.Clone(a.Videos)
+	clone.Audio = slices.Clone(a.Audio)
+	clone.Categories = slices.Clone(a.Categories)
+
+	if a.ProcessedImages != nil {
+		clone.ProcessedImages = make([]ProcessedImage, len(a.ProcessedImages))
+		for i, image := range a.ProcessedImages {
+			sizes := make(map[string]map[string]string, len(image.Sizes))
+			for size, formats := range image.Sizes {
+				sizes[size] = maps.Clone(formats)
+			}
+			clone.ProcessedImages[i] = ProcessedImage{Source: image.Source, Sizes: sizes}
+		}
+	}
+	if a.ProcessedIcons != nil {
+		icons := *a.ProcessedIcons
+		icons.PNG = slices.Clone(icons.PNG)
+		icons.AppleTouch = slices.Clone(icons.AppleTouch)
+		icons.Android = slices.Clone(icons.Android)
+		clone.ProcessedIcons = &icons
+	}
+	return &clone
+}
+
 // UpdateStatus sets a new status and clears all other status flags
 func (a *Article) UpdateStatus(newStatus string) error {
 	// Reset all status flags first
@@ -146,6 +250,7 @@ func (a *Article) UpdateStatus(newStatus string) error {
 	a.Status.Published = 0
 	a.Status.Rejected = 0
 	a.Status.Update = 0
+	a.Status.Unpublish = 0
 
 	// Set the new status
 	switch newStatus {
@@ -161,22 +266,32 @@ func (a *Article) UpdateStatus(newStatus string) error {
 		a.Status.Rejected = 1
 	case "update":
 		a.Status.Update = 1
+	case "unpublish":
+		a.Status.Unpublish = 1
 	default:
 		return fmt.Errorf("invalid status: %s", newStatus)
 	}
 	return nil
 }
 
-// WriteFrontmatter updates the file with modified frontmatter
-func (a *Article) WriteFrontmatter() error {
+// Markdown returns the article as it is written to disk: YAML frontmatter and content
+func (a *Article) Markdown() ([]byte, error) {
 	frontmatter, err := yaml.Marshal(a)
 	if err != nil {
-		return fmt.Errorf("failed to marshal frontmatter: %w", err)
+		return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
 	}
 
-	newContent := fmt.Sprintf("---\n%s---\n\n%s", string(frontmatter), a.Content)
+	return []byte(fmt.Sprintf("---\n%s---\n\n%s", string(frontmatter), a.Content)), nil
+}
+
+// WriteFrontmatter updates the file with modified frontmatter
+func (a *Article) WriteFrontmatter() error {
+	newContent, err := a.Markdown()
+	if err != nil {
+		return err
+	}
 
-	if err := os.WriteFile(a.FilePath, []byte(newContent), 0644); err != nil {
+	if err := os.WriteFile(a.FilePath, newContent, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -3,6 +3,7 @@ package common
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -95,6 +96,11 @@ func TestGetCurrentStatus(t *testing.T) {
 			status:   Status{Draft: 1, Publish: 1, Rejected: 1},
 			expected: "rejected",
 		},
+		{
+			name:     "unpublish after published",
+			status:   Status{Published: 1, Unpublish: 1},
+			expected: "unpublish",
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +170,12 @@ func TestUpdateStatusClearsOtherFlags(t *testing.T) {
 			newStatus:   "draft",
 			wantStatus:  Status{Draft: 1},
 		},
+		{
+			name:        "published to unpublish clears published",
+			startStatus: Status{Published: 1},
+			newStatus:   "unpublish",
+			wantStatus:  Status{Unpublish: 1},
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,3 +198,35 @@ func TestUpdateStatusClearsOtherFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteFrontmatterRoundTrip(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.md")
+	content := "---\nid: \"#ABC123\"\ntitle: Test\nauthor: TB\n---\n\nFirst body\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	article, err := ParseArticle(testFile)
+	if err != nil {
+		t.Fatalf("ParseArticle failed: %v", err)
+	}
+	article.UpdateStatus("publish")
+	if err := article.WriteFrontmatter(); err != nil {
+		t.Fatalf("WriteFrontmatter failed: %v", err)
+	}
+
+	// The body is not duplicated into the frontmatter, so edits to it survive a re-parse
+	data, _ := os.ReadFile(testFile)
+	if strings.Count(string(data), "First body") != 1 {
+		t.Fatalf("Content written more than once:\n%s", data)
+	}
+
+	os.WriteFile(testFile, []byte(strings.Replace(string(data), "First body", "Edited body", 1)), 0644)
+	reparsed, err := ParseArticle(testFile)
+	if err != nil {
+		t.Fatalf("ParseArticle failed: %v", err)
+	}
+	if reparsed.Content != "Edited body" || reparsed.Status.Publish != 1 {
+		t.Errorf("Unexpected article after round trip: content %q, status %+v", reparsed.Content, reparsed.Status)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+
+package common
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// webpSupported reports whether this binary can encode WebP. The encoder wraps libwebp
+// and needs cgo; CGO_ENABLED=0 builds leave the webp format out.
+const webpSupported = true
+
+// encodeWebP writes lossy WebP at quality (1-100)
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+}
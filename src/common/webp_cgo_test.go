@@ -0,0 +1,15 @@
+//go:build cgo
+
+package common
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/chai2010/webp"
+)
+
+// decodeWebP decodes a WebP file written by the encoder
+func decodeWebP(data []byte) (image.Image, error) {
+	return webp.Decode(bytes.NewReader(data))
+}
@@ -0,0 +1,18 @@
+//go:build !cgo
+
+package common
+
+import (
+	"errors"
+	"image"
+	"io"
+)
+
+// webpSupported reports whether this binary can encode WebP. The encoder wraps libwebp
+// and needs cgo; CGO_ENABLED=0 builds leave the webp format out.
+const webpSupported = false
+
+// encodeWebP fails: WebP encoding needs a cgo build
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	return errors.New("webp needs a cgo build (CGO_ENABLED=1)")
+}
@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package common
+
+import (
+	"errors"
+	"image"
+)
+
+// decodeWebP is never called without cgo: no WebP files are written
+func decodeWebP(data []byte) (image.Image, error) {
+	return nil, errors.New("webp needs cgo")
+}
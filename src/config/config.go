@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -10,6 +14,12 @@ import (
 
 type FolderAliases map[string]map[string]string
 
+// defaultFolders are the Danish folder names, used for statuses folder-aliases.yaml does not map
+var defaultFolders = map[string]string{
+	"published": "udgivet",
+	"unpublish": "tilbagetrukket",
+}
+
 // Config represents the application configuration
 type Config struct {
 	Dropbox       DropboxConfig    `yaml:"dropbox"`
@@ -18,6 +28,7 @@ type Config struct {
 	Approval      ApprovalConfig   `yaml:"approval"`
 	Ntfy          NtfyConfig       `yaml:"ntfy"`
 	Hugo          HugoConfig       `yaml:"hugo"`
+	Build         BuildConfig      `yaml:"build"`
 	Git           GitConfig        `yaml:"git"`
 	Rsync         RsyncConfig      `yaml:"rsync"`
 	Images        ImagesConfig     `yaml:"images"`
@@ -38,6 +49,7 @@ type OpenRouterConfig struct {
 }
 
 type EmailConfig struct {
+	Enabled           bool   `yaml:"enabled"`
 	SMTPHost          string `yaml:"smtp_host"`
 	SMTPPort          int    `yaml:"smtp_port"`
 	SMTPUser          string `yaml:"smtp_user"`
@@ -50,12 +62,34 @@ type EmailConfig struct {
 	IMAPPort int    `yaml:"imap_port"`
 	IMAPUser string `yaml:"imap_user"`
 	IMAPPassword string `yaml:"imap_password"`
+	IMAPPollInterval time.Duration `yaml:"imap_poll_interval"` // e.g. "2m"
+
+	// Revision requests are emailed to the author: author as written in front matter → address
+	Authors map[string]string `yaml:"authors"`
 }
 
 type ApprovalConfig struct {
 	Host             string `yaml:"host"`
 	Port             int    `yaml:"port"`
 	TailscaleHostname string `yaml:"tailscale_hostname"`
+	SigningKey       string        `yaml:"signing_key"` // HMAC key for approval links and CSRF tokens
+	LinkTTL          time.Duration `yaml:"link_ttl"`    // How long approval links stay valid, e.g. "72h"
+	Editors          []EditorConfig   `yaml:"editors"`  // If set, every action needs one of these editors
+	Policies         []ApprovalPolicy `yaml:"policies"` // Extra approvals for articles with certain tags
+}
+
+// EditorConfig is an editor allowed to approve articles
+type EditorConfig struct {
+	Name     string `yaml:"name"`
+	Login    string `yaml:"login"`    // Tailscale login (Tailscale-User-Login) and basic auth user
+	Email    string `yaml:"email"`    // Gets a personal approval link; replies from here count as this editor
+	Password string `yaml:"password"` // Basic auth password (optional)
+}
+
+// ApprovalPolicy requires more than one approval for articles with a tag
+type ApprovalPolicy struct {
+	Tag       string `yaml:"tag"`
+	Approvals int    `yaml:"approvals"`
 }
 
 type NtfyConfig struct {
@@ -68,6 +102,18 @@ type HugoConfig struct {
 	SiteDir   string `yaml:"site_dir"`
 	PublicDir string `yaml:"public_dir"`
 	MirrorDir string `yaml:"mirror_dir"`
+	BaseURL   string `yaml:"base_url"` // Public site URL, used for publication_url
+
+	// Preview workspaces, never deployed (default: previews/ next to site_dir)
+	PreviewDir     string `yaml:"preview_dir"`
+	PreviewWorkers int    `yaml:"preview_workers"` // Concurrent preview builds (default 2)
+}
+
+// BuildConfig controls when the site is rebuilt and deployed
+type BuildConfig struct {
+	Interval time.Duration `yaml:"interval"`  // Fallback check for changes, e.g. "10m"
+	Debounce time.Duration `yaml:"debounce"`  // Wait for content changes to settle, e.g. "30s"
+	LockFile string        `yaml:"lock_file"` // flock(2) held while building/deploying; default <site_dir>/.deploy.lock
 }
 
 type ImagesConfig struct {
@@ -86,9 +132,79 @@ type IconsConfig struct {
 }
 
 type DeployConfig struct {
-	Method      string `yaml:"method"`
+	Method      string `yaml:"method"` // Comma-separated targets: rsync, sftp, local, s3
 	RsyncTarget string `yaml:"rsync_target"`
 	RsyncOpts   string `yaml:"rsync_opts"`
+
+	SFTP  SFTPConfig  `yaml:"sftp"`
+	Local LocalConfig `yaml:"local"`
+	S3    S3Config    `yaml:"s3"`
+
+	Releases ReleasesConfig `yaml:"releases"`
+
+	// MaxDeletes stops a deploy that would delete more files than this on the mirror or
+	// any target until it is confirmed. 0 means no limit.
+	MaxDeletes int `yaml:"max_deletes"`
+}
+
+// ReleasesConfig uploads each deploy to releases/<timestamp>/ on the target and then
+// points a current symlink at it, so visitors never see a half-updated site.
+// The web server's document root must be <target>/current.
+type ReleasesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Keep    int  `yaml:"keep"` // Releases kept for rollback, including the live one (default 5)
+}
+
+// SFTPConfig is a webhost reached over SFTP, without an rsync binary
+type SFTPConfig struct {
+	Host       string `yaml:"host"` // host or host:port
+	User       string `yaml:"user"`
+	SSHKey     string `yaml:"ssh_key"`
+	Password   string `yaml:"password"`    // Used if no ssh_key
+	KnownHosts string `yaml:"known_hosts"` // Default ~/.ssh/known_hosts
+	TargetPath string `yaml:"target_path"`
+}
+
+// LocalConfig is a directory on this machine, e.g. a mounted share served by a web server
+type LocalConfig struct {
+	Path string `yaml:"path"`
+}
+
+// S3Config is an S3-compatible bucket (AWS, MinIO, Cloudflare R2 ...)
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"` // e.g. "https://s3.eu-north-1.amazonaws.com"
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"` // Key prefix inside the bucket
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// DeployMethods returns the configured deploy targets. Without deploy.method the
+// legacy rsync.enabled setting decides.
+func (c *Config) DeployMethods() []string {
+	var methods []string
+	for _, method := range strings.Split(c.Deploy.Method, ",") {
+		if method = strings.ToLower(strings.TrimSpace(method)); method != "" && method != "none" {
+			methods = append(methods, method)
+		}
+	}
+	if c.Deploy.Method == "" && c.Rsync.Enabled {
+		methods = []string{"rsync"}
+	}
+	return methods
+}
+
+// StatusDir returns the Dropbox folder for articles with a status, named by
+// folder-aliases.yaml for dropbox.folder_language like the watcher's Mover does
+func (c *Config) StatusDir(status string) string {
+	name, ok := c.Aliases[c.Dropbox.FolderLanguage][status]
+	if !ok {
+		if name, ok = defaultFolders[status]; !ok {
+			name = status
+		}
+	}
+	return filepath.Join(c.Dropbox.BasePath, name)
 }
 
 type GitConfig struct {
@@ -145,9 +261,21 @@ func Load(path string, aliasesPath string) (*Config, error) {
 	if imapPass := os.Getenv("IMAP_PASSWORD"); imapPass != "" {
 		cfg.Email.IMAPPassword = imapPass
 	}
+	if signingKey := os.Getenv("APPROVAL_SIGNING_KEY"); signingKey != "" {
+		cfg.Approval.SigningKey = signingKey
+	}
 	if ntfyTopic := os.Getenv("NTFY_TOPIC"); ntfyTopic != "" {
 		cfg.Ntfy.Topic = ntfyTopic
 	}
+	if sftpPass := os.Getenv("SFTP_PASSWORD"); sftpPass != "" {
+		cfg.Deploy.SFTP.Password = sftpPass
+	}
+	if s3Key := os.Getenv("S3_ACCESS_KEY"); s3Key != "" {
+		cfg.Deploy.S3.AccessKey = s3Key
+	}
+	if s3Secret := os.Getenv("S3_SECRET_KEY"); s3Secret != "" {
+		cfg.Deploy.S3.SecretKey = s3Secret
+	}
 
 	// Validate required fields
 	if err := cfg.Validate(); err != nil {
@@ -168,6 +296,29 @@ func (c *Config) Validate() error {
 	if c.Hugo.SiteDir == "" {
 		return fmt.Errorf("hugo.site_dir is required")
 	}
+	for _, method := range c.DeployMethods() {
+		switch method {
+		case "rsync", "sftp", "local", "s3":
+		default:
+			return fmt.Errorf("deploy.method: unknown target %q", method)
+		}
+		if method == "s3" && c.Deploy.Releases.Enabled {
+			return fmt.Errorf("deploy.releases: not supported by s3 (no symlinks)")
+		}
+	}
+	if _, ok := c.Images.Quality["png"]; ok {
+		// Older configs shipped png: 95 - ignored, PNG is lossless
+		log.Printf("Warning: images.quality.png is deprecated and ignored (PNG is lossless) - remove it")
+	}
+	if c.Deploy.MaxDeletes < 0 {
+		return fmt.Errorf("deploy.max_deletes must be 0 (no limit) or more")
+	}
+	for _, policy := range c.Approval.Policies {
+		// Approvals are counted per editor, so anonymous approvers only ever add up to one
+		if policy.Approvals > 1 && policy.Approvals > len(c.Approval.Editors) {
+			return fmt.Errorf("approval.policies: tag %q needs %d approvals but only %d editors are configured in approval.editors", policy.Tag, policy.Approvals, len(c.Approval.Editors))
+		}
+	}
 	// Add more validation as needed
 	return nil
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -22,12 +24,14 @@ openrouter:
   endpoint: "https://openrouter.ai/api/v1"
 
 email:
+  enabled: true
   smtp_host: "smtp.example.com"
   smtp_port: 587
   smtp_user: "user@example.com"
   smtp_password: "password"
   from_address: "norsetinge@example.com"
   approval_recipient: "editor@example.com"
+  imap_poll_interval: "90s"
 
 approval:
   host: "0.0.0.0"
@@ -45,7 +49,6 @@ images:
   quality:
     webp: 85
     jpeg: 90
-    png: 95
 
 deploy:
   method: "rsync"
@@ -62,8 +65,13 @@ languages:
 		t.Fatalf("Failed to create test config: %v", err)
 	}
 
+	aliasesFile := filepath.Join(tmpDir, "folder-aliases.yaml")
+	if err := os.WriteFile(aliasesFile, []byte("da:\n  draft: \"kladde\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test aliases: %v", err)
+	}
+
 	// Load config
-	cfg, err := Load(configFile)
+	cfg, err := Load(configFile, aliasesFile)
 	if err != nil {
 		t.Fatalf("Load failed: %v", err)
 	}
@@ -83,6 +91,18 @@ languages:
 		t.Error("Expected api_key to be set (from config or env)")
 	}
 
+	if !cfg.Email.Enabled || cfg.Email.SMTPPort != 587 {
+		t.Errorf("Expected email enabled on port 587, got %+v", cfg.Email)
+	}
+
+	if cfg.Email.IMAPPollInterval != 90*time.Second {
+		t.Errorf("Expected imap_poll_interval 90s, got %v", cfg.Email.IMAPPollInterval)
+	}
+
+	if cfg.Aliases["da"]["draft"] != "kladde" {
+		t.Errorf("Expected folder alias 'kladde', got '%s'", cfg.Aliases["da"]["draft"])
+	}
+
 	if cfg.Approval.Port != 8080 {
 		t.Errorf("Expected approval port 8080, got %d", cfg.Approval.Port)
 	}
@@ -135,6 +155,65 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "deprecated png quality is ignored",
+			config: Config{
+				Dropbox: DropboxConfig{
+					BasePath:       "test/path",
+					FolderLanguage: "en",
+				},
+				Hugo: HugoConfig{
+					SiteDir: "site",
+				},
+				Images: ImagesConfig{Quality: map[string]int{"jpeg": 90, "png": 95}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative max_deletes",
+			config: Config{
+				Dropbox: DropboxConfig{
+					BasePath:       "test/path",
+					FolderLanguage: "en",
+				},
+				Hugo: HugoConfig{
+					SiteDir: "site",
+				},
+				Deploy: DeployConfig{MaxDeletes: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "multi-approval policy without editors",
+			config: Config{
+				Dropbox: DropboxConfig{
+					BasePath:       "test/path",
+					FolderLanguage: "en",
+				},
+				Hugo: HugoConfig{
+					SiteDir: "site",
+				},
+				Approval: ApprovalConfig{Policies: []ApprovalPolicy{{Tag: "politik", Approvals: 2}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "multi-approval policy with editors",
+			config: Config{
+				Dropbox: DropboxConfig{
+					BasePath:       "test/path",
+					FolderLanguage: "en",
+				},
+				Hugo: HugoConfig{
+					SiteDir: "site",
+				},
+				Approval: ApprovalConfig{
+					Editors:  []EditorConfig{{Login: "tb"}, {Login: "ab"}},
+					Policies: []ApprovalPolicy{{Tag: "politik", Approvals: 2}},
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -146,3 +225,59 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDeployMethods(t *testing.T) {
+	tests := []struct {
+		method       string
+		rsyncEnabled bool
+		want         []string
+	}{
+		{"", false, nil},
+		{"", true, []string{"rsync"}},
+		{"none", true, nil},
+		{"rsync", false, []string{"rsync"}},
+		{"SFTP, s3 ,", false, []string{"sftp", "s3"}},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{Deploy: DeployConfig{Method: tt.method}, Rsync: RsyncConfig{Enabled: tt.rsyncEnabled}}
+		got := cfg.DeployMethods()
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("DeployMethods(%q, rsync.enabled=%v) = %v, want %v", tt.method, tt.rsyncEnabled, got, tt.want)
+		}
+	}
+
+	cfg := &Config{
+		Dropbox: DropboxConfig{BasePath: "/tmp", FolderLanguage: "da"},
+		Hugo:    HugoConfig{SiteDir: "site"},
+		Deploy:  DeployConfig{Method: "rsync,ftp"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected unknown deploy method to fail validation")
+	}
+
+	cfg.Deploy = DeployConfig{Method: "s3", Releases: ReleasesConfig{Enabled: true}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected release mode with s3 to fail validation")
+	}
+}
+
+func TestStatusDir(t *testing.T) {
+	cfg := &Config{
+		Dropbox: DropboxConfig{BasePath: "/dropbox", FolderLanguage: "en"},
+		Aliases: FolderAliases{
+			"en": {"published": "published", "unpublish": "unpublished"},
+			"da": {"published": "udgivet", "unpublish": "tilbagetrukket"},
+		},
+	}
+
+	if dir := cfg.StatusDir("unpublish"); dir != filepath.Join("/dropbox", "unpublished") {
+		t.Errorf("Expected the English withdrawn folder, got %s", dir)
+	}
+
+	// Without aliases the Danish names are used
+	cfg.Aliases = nil
+	if dir := cfg.StatusDir("published"); dir != filepath.Join("/dropbox", "udgivet") {
+		t.Errorf("Expected the default published folder, got %s", dir)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"norsetinge/src/config"
@@ -14,6 +15,9 @@ import (
 // Deployer handles deployment pipeline
 type Deployer struct {
 	cfg *config.Config
+
+	mu             sync.Mutex
+	allowedDeletes int // Set by AllowDeletes for the next deploy
 }
 
 // NewDeployer creates a new deployer
@@ -25,6 +29,18 @@ func NewDeployer(cfg *config.Config) *Deployer {
 func (d *Deployer) Deploy(publicDir, mirrorDir string) error {
 	log.Printf("🚀 Starting deployment pipeline...")
 
+	// 0. Dry-run first if deletes are limited, and stop before anything is written
+	allowed := d.takeAllowedDeletes()
+	if limit := d.cfg.Deploy.MaxDeletes; limit > 0 {
+		report, err := d.Plan(publicDir, mirrorDir)
+		if err != nil {
+			return fmt.Errorf("failed to plan deploy: %w", err)
+		}
+		if deletes := report.Deletes(); deletes > limit && deletes > allowed {
+			return &DeleteThresholdError{Report: report, Limit: limit}
+		}
+	}
+
 	// 1. Sync public to mirror
 	if err := d.syncToMirror(publicDir, mirrorDir); err != nil {
 		return fmt.Errorf("failed to sync to mirror: %w", err)
@@ -37,11 +53,13 @@ func (d *Deployer) Deploy(publicDir, mirrorDir string) error {
 		}
 	}
 
-	// 3. Rsync to webhost
-	if d.cfg.Rsync.Enabled {
-		if err := d.rsyncToWebhost(mirrorDir); err != nil {
-			return fmt.Errorf("failed to rsync to webhost: %w", err)
-		}
+	// 3. Deploy to the targets selected by deploy.method
+	targets, err := newTargets(d.cfg)
+	if err != nil {
+		return fmt.Errorf("invalid deploy targets: %w", err)
+	}
+	if err := deployTargets(targets, mirrorDir, d.cfg.Deploy.Releases); err != nil {
+		return fmt.Errorf("failed to deploy: %w", err)
 	}
 
 	log.Printf("✅ Deployment complete!")
@@ -108,7 +126,7 @@ func (d *Deployer) gitCommitAndPush(mirrorDir string) error {
 	}
 
 	// Git commit with timestamp
-	commitMsg := fmt.Sprintf("Deploy: %s", time.Now().Format("2006-01-02 15:04:05"))
+	commitMsg := deployCommitPrefix + time.Now().Format("2006-01-02 15:04:05")
 	cmd = exec.Command("git", "commit", "-m", commitMsg)
 	cmd.Dir = mirrorDir
 	if output, err := cmd.CombinedOutput(); err != nil {
@@ -154,36 +172,3 @@ func (d *Deployer) gitInit(mirrorDir string) error {
 	log.Printf("✓ Git repository initialized")
 	return nil
 }
-
-// rsyncToWebhost syncs mirror to webhost using rsync
-func (d *Deployer) rsyncToWebhost(mirrorDir string) error {
-	log.Printf("🌐 Deploying to webhost via rsync...")
-
-	// Build rsync command with --delete flag
-	target := fmt.Sprintf("%s@%s:%s", d.cfg.Rsync.User, d.cfg.Rsync.Host, d.cfg.Rsync.TargetPath)
-
-	args := []string{
-		"-avz",
-		"--delete", // Remove files on remote that don't exist in source
-		"--exclude", ".git",
-	}
-
-	// Add SSH key if specified
-	if d.cfg.Rsync.SSHKey != "" {
-		args = append(args, "-e", fmt.Sprintf("ssh -i %s", d.cfg.Rsync.SSHKey))
-	}
-
-	// Add source and target
-	args = append(args, mirrorDir+"/", target)
-
-	cmd := exec.Command("rsync", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("rsync failed: %s", string(output))
-	}
-
-	log.Printf("✓ Deployed to: %s", target)
-	return nil
-}
-
-
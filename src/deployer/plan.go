@@ -0,0 +1,389 @@
+package deployer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangeSet lists the files, by slash-separated site path, a deploy adds, changes and
+// deletes at one destination
+type ChangeSet struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Deleted []string `json:"deleted"`
+}
+
+// emptyChanges returns a change set with empty lists, so JSON has [] rather than null
+func emptyChanges() ChangeSet {
+	return ChangeSet{Added: []string{}, Changed: []string{}, Deleted: []string{}}
+}
+
+// Empty reports whether the deploy would leave the destination as it is
+func (c ChangeSet) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Deleted) == 0
+}
+
+func (c ChangeSet) String() string {
+	return fmt.Sprintf("%d added, %d changed, %d deleted", len(c.Added), len(c.Changed), len(c.Deleted))
+}
+
+// updated returns the added and changed paths as a set
+func (c ChangeSet) updated() map[string]bool {
+	paths := make(map[string]bool, len(c.Added)+len(c.Changed))
+	for _, p := range c.Added {
+		paths[p] = true
+	}
+	for _, p := range c.Changed {
+		paths[p] = true
+	}
+	return paths
+}
+
+// TargetReport is the change set for one deploy target. Error is set if the target
+// could not be compared, e.g. because the webhost was unreachable.
+type TargetReport struct {
+	Name string `json:"name"`
+	ChangeSet
+	Error string `json:"error,omitempty"`
+}
+
+// DeployReport is the result of a dry-run: what a deploy would change in the mirror
+// and on each target
+type DeployReport struct {
+	Generated time.Time      `json:"generated"`
+	Mirror    ChangeSet      `json:"mirror"`
+	Targets   []TargetReport `json:"targets"`
+}
+
+// Deletes returns the most files the deploy would delete at any one destination
+func (r *DeployReport) Deletes() int {
+	deletes := len(r.Mirror.Deleted)
+	for _, target := range r.Targets {
+		deletes = max(deletes, len(target.Deleted))
+	}
+	return deletes
+}
+
+// DeleteThresholdError stops a deploy that would delete more than deploy.max_deletes
+// files. Report holds the dry-run; AllowDeletes lets the next deploy through.
+type DeleteThresholdError struct {
+	Report *DeployReport
+	Limit  int
+}
+
+func (e *DeleteThresholdError) Error() string {
+	return fmt.Sprintf("deploy would delete %d files (deploy.max_deletes is %d) - review the dry-run and confirm", e.Report.Deletes(), e.Limit)
+}
+
+// targetPlanner is a target that can report what Deploy would change without writing
+type targetPlanner interface {
+	// Plan compares sourceDir with the target; with releases, with the current release
+	Plan(sourceDir string, releases bool) (ChangeSet, error)
+}
+
+// Plan reports what deploying publicDir would change in the mirror and on every target,
+// without writing anything. The targets are compared with publicDir, which the mirror
+// sync copies with content and mtimes unchanged.
+func (d *Deployer) Plan(publicDir, mirrorDir string) (*DeployReport, error) {
+	mirror, err := mirrorChanges(publicDir, mirrorDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare mirror: %w", err)
+	}
+
+	targets, err := newTargets(d.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deploy targets: %w", err)
+	}
+
+	report := &DeployReport{Generated: time.Now(), Mirror: mirror, Targets: []TargetReport{}}
+	for _, target := range targets {
+		result := TargetReport{Name: target.Name(), ChangeSet: emptyChanges()}
+		if planner, ok := target.(targetPlanner); !ok {
+			result.Error = "dry-run is not supported"
+		} else if changes, err := planner.Plan(publicDir, d.cfg.Deploy.Releases.Enabled); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.ChangeSet = changes
+		}
+		report.Targets = append(report.Targets, result)
+	}
+	return report, nil
+}
+
+// AllowDeletes lets the next deploy delete up to n files, above deploy.max_deletes.
+// It is used once a dry-run stopped by DeleteThresholdError has been reviewed.
+func (d *Deployer) AllowDeletes(n int) {
+	d.mu.Lock()
+	d.allowedDeletes = n
+	d.mu.Unlock()
+}
+
+// takeAllowedDeletes returns and clears the allowance from AllowDeletes
+func (d *Deployer) takeAllowedDeletes() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	allowed := d.allowedDeletes
+	d.allowedDeletes = 0
+	return allowed
+}
+
+// mirrorChanges compares public with the mirror by content, since the mirror's mtimes
+// say nothing about whether the site changed
+func mirrorChanges(publicDir, mirrorDir string) (ChangeSet, error) {
+	changes := emptyChanges()
+
+	files, err := listSource(publicDir)
+	if err != nil {
+		return changes, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, file := range files {
+		wanted[file.Path] = true
+		dest := filepath.Join(mirrorDir, filepath.FromSlash(file.Path))
+
+		info, err := os.Stat(dest)
+		if err != nil {
+			changes.Added = append(changes.Added, file.Path)
+			continue
+		}
+		if info.Size() != file.Size {
+			changes.Changed = append(changes.Changed, file.Path)
+			continue
+		}
+		same, err := sameContent(filepath.Join(publicDir, filepath.FromSlash(file.Path)), dest)
+		if err != nil {
+			return changes, err
+		}
+		if !same {
+			changes.Changed = append(changes.Changed, file.Path)
+		}
+	}
+
+	existing, err := listDest(mirrorDir)
+	if err != nil {
+		return changes, err
+	}
+	changes.Deleted = staleFiles(existing, wanted)
+	return changes, nil
+}
+
+// sameContent reports whether two files have the same bytes
+func sameContent(a, b string) (bool, error) {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// localChanges compares the source files with dir by size and mtime, like rsync
+func localChanges(files []sourceFile, dir string) (ChangeSet, error) {
+	changes := emptyChanges()
+
+	wanted := make(map[string]bool)
+	for _, file := range files {
+		wanted[file.Path] = true
+
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			changes.Added = append(changes.Added, file.Path)
+		} else if info.Size() != file.Size || !info.ModTime().Equal(file.ModTime) {
+			changes.Changed = append(changes.Changed, file.Path)
+		}
+	}
+
+	existing, err := listDest(dir)
+	if err != nil {
+		return changes, err
+	}
+	changes.Deleted = staleFiles(existing, wanted)
+	return changes, nil
+}
+
+// allAdded is the change set for a first deploy
+func allAdded(files []sourceFile) ChangeSet {
+	changes := emptyChanges()
+	for _, file := range files {
+		changes.Added = append(changes.Added, file.Path)
+	}
+	return changes
+}
+
+// listDest returns the paths of everything but directories in a deploy destination,
+// skipping .git. A destination that does not exist yet is empty.
+func listDest(dir string) ([]string, error) {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	var paths []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == dir && os.IsNotExist(err) {
+				return nil // First deploy
+			}
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// staleFiles returns the existing paths that are not wanted, sorted
+func staleFiles(existing []string, wanted map[string]bool) []string {
+	stale := []string{}
+	for _, p := range existing {
+		if !wanted[p] {
+			stale = append(stale, p)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// Plan compares with the directory, or with the current release
+func (t *localTarget) Plan(sourceDir string, releases bool) (ChangeSet, error) {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+
+	dir := t.path
+	if releases {
+		previous, err := localCurrentRelease(filepath.Join(t.path, "current"))
+		if err != nil {
+			return ChangeSet{}, err
+		}
+		if previous == "" {
+			return allAdded(files), nil
+		}
+		dir = filepath.Join(t.path, "releases", previous)
+	}
+	return localChanges(files, dir)
+}
+
+// Plan compares with the remote directory, or with the current release
+func (t *sftpTarget) Plan(sourceDir string, releases bool) (ChangeSet, error) {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+
+	client, err := t.connect()
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	defer client.Close()
+
+	dir := t.cfg.TargetPath
+	if releases {
+		currentLink := path.Join(t.cfg.TargetPath, "current")
+		info, err := client.Lstat(currentLink)
+		if err != nil {
+			return allAdded(files), nil // First release
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return ChangeSet{}, fmt.Errorf("%s exists and is not a symlink - move it away before enabling deploy.releases", currentLink)
+		}
+		target, err := client.ReadLink(currentLink)
+		if err != nil {
+			return ChangeSet{}, fmt.Errorf("failed to read current symlink: %w", err)
+		}
+		dir = path.Join(t.cfg.TargetPath, "releases", path.Base(target))
+	}
+
+	changes, _, err := sftpChanges(client, files, dir)
+	return changes, err
+}
+
+// Plan compares with the bucket; s3 has no release mode
+func (t *s3Target) Plan(sourceDir string, releases bool) (ChangeSet, error) {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	return t.changes(sourceDir, files)
+}
+
+// Plan runs rsync with --dry-run --itemize-changes and parses its report. With releases
+// it compares with the current release, which the next release hardlinks from.
+func (t *rsyncTarget) Plan(sourceDir string, releases bool) (ChangeSet, error) {
+	dest := t.target
+	if releases {
+		host, basePath, ok := strings.Cut(t.target, ":")
+		if !ok || host == "" || basePath == "" {
+			return ChangeSet{}, fmt.Errorf("release mode needs a remote rsync target (user@host:/path), got %q", t.target)
+		}
+		dest = fmt.Sprintf("%s:%s/current/", host, strings.TrimSuffix(basePath, "/"))
+	}
+
+	args := append([]string{}, t.opts...)
+	args = append(args, "--dry-run", "--itemize-changes", "--exclude", ".git")
+	if t.sshKey != "" {
+		args = append(args, "-e", fmt.Sprintf("ssh -i %s", t.sshKey))
+	}
+	args = append(args, sourceDir+"/", dest)
+
+	cmd := exec.Command("rsync", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return ChangeSet{}, fmt.Errorf("rsync dry-run failed: %w\nOutput: %s", err, string(output))
+	}
+	return parseItemized(string(output)), nil
+}
+
+// parseItemized reads the output of rsync --itemize-changes ("YXcstpoguax path").
+// Only files are reported; directories, symlinks and attribute-only updates are left out.
+func parseItemized(output string) ChangeSet {
+	changes := emptyChanges()
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) < 13 || line[11] != ' ' || !strings.ContainsRune("<>ch.*", rune(line[0])) {
+			continue // Not an itemized line, e.g. "sending incremental file list"
+		}
+		item, name := line[:11], line[12:]
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(item, "*deleting"):
+			changes.Deleted = append(changes.Deleted, name)
+		case item[1] != 'f':
+			continue
+		case item[2:] == "+++++++++":
+			changes.Added = append(changes.Added, name)
+		case item[0] == '<' || item[0] == '>':
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	sort.Strings(changes.Deleted) // rsync deletes deepest first
+	return changes
+}
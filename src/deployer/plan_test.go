@@ -0,0 +1,123 @@
+package deployer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"norsetinge/src/config"
+)
+
+func TestParseItemized(t *testing.T) {
+	output := `sending incremental file list
+*deleting   old/index.html
+*deleting   old/
+.d..t...... ./
+>f+++++++++ posts/new/index.html
+>f.st...... index.html
+.f...p..... style.css
+cd+++++++++ posts/new/
+cL+++++++++ latest -> posts/new
+*deleting   archive.html
+
+sent 1,234 bytes  received 56 bytes  2,580.00 bytes/sec
+total size is 98,765  speedup is 76.56 (DRY RUN)
+`
+	want := ChangeSet{
+		Added:   []string{"posts/new/index.html"},
+		Changed: []string{"index.html"},
+		Deleted: []string{"archive.html", "old/index.html"},
+	}
+	if got := parseItemized(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseItemized:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestMirrorChanges(t *testing.T) {
+	public := t.TempDir()
+	mirror := t.TempDir()
+	writeSite(t, public, map[string]string{"index.html": "v2", "style.css": "body {}", "new.html": "new"})
+	writeSite(t, mirror, map[string]string{"index.html": "v1", "style.css": "body {}", "gone.html": "old", ".git/HEAD": "main"})
+
+	changes, err := mirrorChanges(public, mirror)
+	if err != nil {
+		t.Fatalf("mirrorChanges failed: %v", err)
+	}
+	want := ChangeSet{Added: []string{"new.html"}, Changed: []string{"index.html"}, Deleted: []string{"gone.html"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("mirrorChanges:\n got %+v\nwant %+v", changes, want)
+	}
+
+	// An empty mirror before the first deploy
+	changes, err = mirrorChanges(public, filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(changes.Added) != 3 || len(changes.Deleted) != 0 {
+		t.Errorf("Expected everything added to a missing mirror, got %+v (%v)", changes, err)
+	}
+}
+
+func TestLocalTargetPlan(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	target := &localTarget{path: dest}
+
+	writeSite(t, source, map[string]string{"index.html": "home", "posts/a/index.html": "a"})
+	writeSite(t, dest, map[string]string{"stale.html": "old"})
+
+	changes, err := target.Plan(source, false)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(changes.Added) != 2 || !reflect.DeepEqual(changes.Deleted, []string{"stale.html"}) {
+		t.Errorf("Unexpected plan: %+v", changes)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "stale.html")); err != nil {
+		t.Error("Plan deleted a file")
+	}
+
+	// After the deploy it planned there is nothing left to do
+	if err := target.Deploy(source); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if changes, _ := target.Plan(source, false); !changes.Empty() {
+		t.Errorf("Expected an empty plan after deploying, got %+v", changes)
+	}
+}
+
+func TestDeployStopsAtDeleteThreshold(t *testing.T) {
+	public := t.TempDir()
+	mirror := t.TempDir()
+	dest := t.TempDir()
+	writeSite(t, public, map[string]string{"index.html": "home"})
+	writeSite(t, mirror, map[string]string{"index.html": "home", "a.html": "a", "b.html": "b"})
+	writeSite(t, dest, map[string]string{"index.html": "home", "a.html": "a", "b.html": "b", "c.html": "c"})
+
+	d := NewDeployer(&config.Config{Deploy: config.DeployConfig{
+		Method:     "local",
+		Local:      config.LocalConfig{Path: dest},
+		MaxDeletes: 2,
+	}})
+
+	err := d.Deploy(public, mirror)
+	var thresholdErr *DeleteThresholdError
+	if !errors.As(err, &thresholdErr) {
+		t.Fatalf("Expected DeleteThresholdError, got %v", err)
+	}
+	if thresholdErr.Report.Deletes() != 3 || len(thresholdErr.Report.Targets) != 1 {
+		t.Errorf("Unexpected report: %+v", thresholdErr.Report)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "c.html")); err != nil {
+		t.Error("A stopped deploy deleted files")
+	}
+
+	// A confirmed allowance below the planned deletes still stops the deploy
+	d.AllowDeletes(2)
+	if err := d.Deploy(public, mirror); !errors.As(err, &thresholdErr) {
+		t.Errorf("Expected an allowance of 2 to stop 3 deletes, got %v", err)
+	}
+	// The allowance is used up by one deploy
+	if allowed := d.takeAllowedDeletes(); allowed != 0 {
+		t.Errorf("Expected the allowance to be cleared, got %d", allowed)
+	}
+}
@@ -0,0 +1,188 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultKeepReleases is the number of releases kept when deploy.releases.keep is not set
+	defaultKeepReleases = 5
+
+	// releaseFormat names release directories; it sorts chronologically
+	releaseFormat = "20060102T150405Z"
+)
+
+// releaseTarget is a target that can upload into releases/<name>/ and then atomically
+// point current at it (deploy.releases)
+type releaseTarget interface {
+	DeployTarget
+
+	// DeployRelease uploads sourceDir as a new release, swaps current to it and
+	// removes all but the newest keep releases
+	DeployRelease(sourceDir, release string, keep int) error
+}
+
+// staleReleases returns the releases to remove: all but the newest keep, never current
+func staleReleases(releases []string, current string, keep int) []string {
+	sorted := append([]string{}, releases...)
+	sort.Strings(sorted)
+
+	var stale []string
+	for i := 0; i < len(sorted)-keep; i++ {
+		if sorted[i] != current {
+			stale = append(stale, sorted[i])
+		}
+	}
+	return stale
+}
+
+// DeployRelease copies the site into releases/<release>/, hardlinking files that are
+// unchanged since the current release, then replaces the current symlink with rename(2)
+func (t *localTarget) DeployRelease(sourceDir, release string, keep int) error {
+	releasesDir := filepath.Join(t.path, "releases")
+	releaseDir := filepath.Join(releasesDir, release)
+	currentLink := filepath.Join(t.path, "current")
+
+	previous, err := localCurrentRelease(currentLink)
+	if err != nil {
+		return err
+	}
+
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		src := filepath.Join(sourceDir, filepath.FromSlash(file.Path))
+		dest := filepath.Join(releaseDir, filepath.FromSlash(file.Path))
+
+		if previous != "" {
+			old := filepath.Join(releasesDir, previous, filepath.FromSlash(file.Path))
+			if info, err := os.Stat(old); err == nil && info.Size() == file.Size && info.ModTime().Equal(file.ModTime) {
+				if os.MkdirAll(filepath.Dir(dest), 0755) == nil && os.Link(old, dest) == nil {
+					continue
+				}
+			}
+		}
+		if err := copyFile(src, dest, file.ModTime); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", file.Path, err)
+		}
+	}
+	if err := os.MkdirAll(releaseDir, 0755); err != nil { // Empty site
+		return err
+	}
+
+	// Build the new link beside the old one and rename it over, so current always exists
+	tmpLink := currentLink + ".tmp"
+	os.Remove(tmpLink)
+	if err := os.Symlink(path.Join("releases", release), tmpLink); err != nil {
+		return fmt.Errorf("failed to create current symlink: %w", err)
+	}
+	if err := os.Rename(tmpLink, currentLink); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("failed to swap current symlink: %w", err)
+	}
+
+	entries, err := os.ReadDir(releasesDir)
+	if err != nil {
+		return fmt.Errorf("failed to list releases: %w", err)
+	}
+	var releases []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			releases = append(releases, entry.Name())
+		}
+	}
+	for _, stale := range staleReleases(releases, release, keep) {
+		if err := os.RemoveAll(filepath.Join(releasesDir, stale)); err != nil {
+			return fmt.Errorf("failed to remove release %s: %w", stale, err)
+		}
+	}
+	return nil
+}
+
+// localCurrentRelease returns the release current points at, or "" before the first release
+func localCurrentRelease(currentLink string) (string, error) {
+	info, err := os.Lstat(currentLink)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", fmt.Errorf("%s exists and is not a symlink - move it away before enabling deploy.releases", currentLink)
+	}
+
+	target, err := os.Readlink(currentLink)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(target), nil
+}
+
+// DeployRelease rsyncs into releases/<release>/ with --link-dest on the current release,
+// then swaps the current symlink and prunes old releases over ssh
+func (t *rsyncTarget) DeployRelease(sourceDir, release string, keep int) error {
+	host, basePath, ok := strings.Cut(t.target, ":")
+	if !ok || host == "" || basePath == "" {
+		return fmt.Errorf("release mode needs a remote rsync target (user@host:/path), got %q", t.target)
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	if err := t.ssh(host, "mkdir -p "+shellQuote(basePath+"/releases")); err != nil {
+		return err
+	}
+
+	// --link-dest is relative to the release directory; unchanged files become hardlinks
+	args := append([]string{}, t.opts...)
+	args = append(args, "--exclude", ".git", "--link-dest", "../../current/")
+	if t.sshKey != "" {
+		args = append(args, "-e", fmt.Sprintf("ssh -i %s", t.sshKey))
+	}
+	args = append(args, sourceDir+"/", fmt.Sprintf("%s:%s/releases/%s/", host, basePath, release))
+
+	cmd := exec.Command("rsync", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("rsync failed: %w\nOutput: %s", err, string(output))
+	}
+
+	return t.ssh(host, releaseSwapScript(basePath, release, keep))
+}
+
+// ssh runs a shell command on the rsync host
+func (t *rsyncTarget) ssh(host, command string) error {
+	args := []string{"-o", "BatchMode=yes"}
+	if t.sshKey != "" {
+		args = append(args, "-i", t.sshKey)
+	}
+	args = append(args, host, command)
+
+	cmd := exec.Command("ssh", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ssh %s failed: %w\nOutput: %s", host, err, string(output))
+	}
+	return nil
+}
+
+// releaseSwapScript points current at release with an atomic rename (GNU mv -T) and
+// removes all but the newest keep releases
+func releaseSwapScript(basePath, release string, keep int) string {
+	return strings.Join([]string{
+		"cd " + shellQuote(basePath),
+		"ln -sfn " + shellQuote("releases/"+release) + " current.tmp",
+		"mv -Tf current.tmp current",
+		fmt.Sprintf("ls -1 releases | sort | head -n -%d | grep -vxF %s | while read -r old; do rm -rf \"releases/$old\"; done", keep, shellQuote(release)),
+	}, " && ")
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
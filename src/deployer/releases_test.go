@@ -0,0 +1,141 @@
+package deployer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStaleReleases(t *testing.T) {
+	releases := []string{"20250103T000000Z", "20250101T000000Z", "20250104T000000Z", "20250102T000000Z"}
+
+	if got := staleReleases(releases, "20250104T000000Z", 2); !reflect.DeepEqual(got, []string{"20250101T000000Z", "20250102T000000Z"}) {
+		t.Errorf("Expected the two oldest to be stale, got %v", got)
+	}
+	if got := staleReleases(releases, "20250104T000000Z", 10); len(got) != 0 {
+		t.Errorf("Expected nothing stale below keep, got %v", got)
+	}
+	// current is never removed, even if it is old
+	if got := staleReleases(releases, "20250101T000000Z", 1); !reflect.DeepEqual(got, []string{"20250102T000000Z", "20250103T000000Z"}) {
+		t.Errorf("Expected current to be kept, got %v", got)
+	}
+}
+
+// assertCurrent checks that base/current links to the release and serves content
+func assertCurrent(t *testing.T, base, release, index string) {
+	t.Helper()
+	link, err := os.Readlink(filepath.Join(base, "current"))
+	if err != nil || link != "releases/"+release {
+		t.Fatalf("Expected current → releases/%s, got %q (%v)", release, link, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(base, "current", "index.html")); string(data) != index {
+		t.Errorf("current/index.html: got %q, want %q", data, index)
+	}
+}
+
+// sameFile reports whether two paths are hardlinks of one inode
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && infoA.Sys().(*syscall.Stat_t).Ino == infoB.Sys().(*syscall.Stat_t).Ino
+}
+
+func TestLocalTargetRelease(t *testing.T) {
+	source := t.TempDir()
+	base := t.TempDir()
+	target := &localTarget{path: base}
+
+	writeSite(t, source, map[string]string{"index.html": "v1", "style.css": "body {}"})
+	if err := target.DeployRelease(source, "20250101T000000Z", 2); err != nil {
+		t.Fatalf("First release failed: %v", err)
+	}
+	assertCurrent(t, base, "20250101T000000Z", "v1")
+
+	writeSite(t, source, map[string]string{"index.html": "v2"})
+	if err := target.DeployRelease(source, "20250102T000000Z", 2); err != nil {
+		t.Fatalf("Second release failed: %v", err)
+	}
+	assertCurrent(t, base, "20250102T000000Z", "v2")
+
+	// The previous release is untouched and shares unchanged files
+	if data, _ := os.ReadFile(filepath.Join(base, "releases", "20250101T000000Z", "index.html")); string(data) != "v1" {
+		t.Errorf("Previous release was modified: %q", data)
+	}
+	if !sameFile(filepath.Join(base, "releases", "20250101T000000Z", "style.css"), filepath.Join(base, "releases", "20250102T000000Z", "style.css")) {
+		t.Error("Unchanged file was copied instead of hardlinked")
+	}
+
+	writeSite(t, source, map[string]string{"index.html": "v3"})
+	if err := target.DeployRelease(source, "20250103T000000Z", 2); err != nil {
+		t.Fatalf("Third release failed: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(base, "releases"))
+	if len(entries) != 2 || entries[0].Name() != "20250102T000000Z" {
+		t.Errorf("Expected the two newest releases to be kept, got %v", entries)
+	}
+
+	// A real directory in place of current is refused rather than replaced
+	other := t.TempDir()
+	os.MkdirAll(filepath.Join(other, "current"), 0755)
+	if err := (&localTarget{path: other}).DeployRelease(source, "20250101T000000Z", 2); err == nil {
+		t.Error("Expected an existing current directory to be refused")
+	}
+}
+
+func TestReleaseSwapScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	base := filepath.Join(t.TempDir(), "site with 'quotes'")
+	for _, release := range []string{"20250101T000000Z", "20250102T000000Z", "20250103T000000Z"} {
+		writeSite(t, filepath.Join(base, "releases", release), map[string]string{"index.html": release})
+	}
+	os.Symlink("releases/20250102T000000Z", filepath.Join(base, "current"))
+
+	output, err := exec.Command("sh", "-c", releaseSwapScript(base, "20250103T000000Z", 2)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Swap script failed: %v\n%s", err, output)
+	}
+
+	assertCurrent(t, base, "20250103T000000Z", "20250103T000000Z")
+	if _, err := os.Stat(filepath.Join(base, "releases", "20250101T000000Z")); !os.IsNotExist(err) {
+		t.Error("Oldest release was not pruned")
+	}
+	if _, err := os.Stat(filepath.Join(base, "releases", "20250102T000000Z")); err != nil {
+		t.Error("Previous release was pruned")
+	}
+}
+
+func TestReleaseSFTP(t *testing.T) {
+	client := newSFTPPair(t)
+	source := t.TempDir()
+	base := filepath.Join(t.TempDir(), "www")
+
+	writeSite(t, source, map[string]string{"index.html": "v1", "style.css": "body {}", ".git/HEAD": "main"})
+	if err := releaseSFTP(client, source, base, "20250101T000000Z", 1); err != nil {
+		t.Fatalf("First release failed: %v", err)
+	}
+	assertCurrent(t, base, "20250101T000000Z", "v1")
+
+	// SFTP mtimes have whole seconds; make the change visible to the size+mtime check
+	writeSite(t, source, map[string]string{"index.html": "v2"})
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(source, "index.html"), future, future)
+	if err := releaseSFTP(client, source, base, "20250102T000000Z", 1); err != nil {
+		t.Fatalf("Second release failed: %v", err)
+	}
+	assertCurrent(t, base, "20250102T000000Z", "v2")
+
+	entries, _ := os.ReadDir(filepath.Join(base, "releases"))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the live release to be kept, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(base, "current", ".git")); !os.IsNotExist(err) {
+		t.Error("The mirror's .git directory was uploaded")
+	}
+}
@@ -0,0 +1,141 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// deployCommitPrefix starts the subject of the mirror commits made by Deploy
+	deployCommitPrefix = "Deploy: "
+
+	// rollbackCommitPrefix starts the subject of the mirror commits made by Rollback
+	rollbackCommitPrefix = "Rollback: "
+)
+
+// ErrUnknownCommit is returned by Rollback for a commit that is not a deploy in the mirror
+var ErrUnknownCommit = errors.New("not a deploy commit")
+
+// commitPattern matches the (abbreviated) commit hashes Rollback accepts
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// DeployCommit is a deploy recorded in the mirror's git history
+type DeployCommit struct {
+	Hash    string    `json:"hash"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+}
+
+// ShortHash returns the abbreviated commit hash
+func (c DeployCommit) ShortHash() string {
+	if len(c.Hash) > 10 {
+		return c.Hash[:10]
+	}
+	return c.Hash
+}
+
+// ListDeploys returns the newest deploy commits in the mirror, newest first
+func (d *Deployer) ListDeploys(mirrorDir string, limit int) ([]DeployCommit, error) {
+	if err := requireGitMirror(mirrorDir); err != nil {
+		return nil, err
+	}
+
+	output, err := git(mirrorDir, "log", "--grep", "^"+deployCommitPrefix,
+		fmt.Sprintf("--max-count=%d", limit), "--format=%H%x00%cI%x00%s")
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []DeployCommit
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], deployCommitPrefix) {
+			continue // --grep also matches the message body
+		}
+		committed, _ := time.Parse(time.RFC3339, fields[1])
+		commits = append(commits, DeployCommit{Hash: fields[0], Time: committed, Subject: fields[2]})
+	}
+	return commits, nil
+}
+
+// Rollback puts the site of an earlier deploy commit back into the mirror and deploys
+// it to the targets. The old tree is committed on top as a "Rollback: ..." commit, so
+// newer history is kept and the rollback itself is recorded. The next build that finds
+// changed content deploys the current site again.
+func (d *Deployer) Rollback(mirrorDir, commit string) (*DeployCommit, error) {
+	if !commitPattern.MatchString(commit) {
+		return nil, fmt.Errorf("%w: invalid hash %q", ErrUnknownCommit, commit)
+	}
+	if err := requireGitMirror(mirrorDir); err != nil {
+		return nil, err
+	}
+
+	output, err := git(mirrorDir, "log", "-1", "--format=%H%x00%cI%x00%s", commit+"^{commit}", "--")
+	if err != nil {
+		log.Printf("Warning: Failed to look up rollback commit: %v", err)
+		return nil, fmt.Errorf("%w: unknown commit %s", ErrUnknownCommit, commit)
+	}
+	fields := strings.Split(strings.TrimSpace(output), "\x00")
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], deployCommitPrefix) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommit, commit)
+	}
+	committed, _ := time.Parse(time.RFC3339, fields[1])
+	target := &DeployCommit{Hash: fields[0], Time: committed, Subject: fields[2]}
+
+	log.Printf("⏪ Rolling back to %s (%s)...", target.ShortHash(), target.Subject)
+
+	// Replace index and working tree with the old tree; HEAD and history stay put
+	if _, err := git(mirrorDir, "read-tree", "-u", "--reset", target.Hash); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", target.ShortHash(), err)
+	}
+
+	message := fmt.Sprintf("%s%s to %s (%s)", rollbackCommitPrefix, time.Now().Format("2006-01-02 15:04:05"), target.ShortHash(), target.Subject)
+	if _, err := git(mirrorDir, "commit", "--allow-empty", "-m", message); err != nil {
+		return nil, fmt.Errorf("failed to record rollback: %w", err)
+	}
+	if d.cfg.Git.AutoCommit {
+		if _, err := git(mirrorDir, "push"); err != nil {
+			return nil, fmt.Errorf("failed to push rollback: %w", err)
+		}
+	}
+
+	targets, err := newTargets(d.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deploy targets: %w", err)
+	}
+	if err := deployTargets(targets, mirrorDir, d.cfg.Deploy.Releases); err != nil {
+		return nil, fmt.Errorf("failed to deploy rollback: %w", err)
+	}
+
+	log.Printf("✅ Rolled back to %s", target.ShortHash())
+	return target, nil
+}
+
+// requireGitMirror fails unless the mirror has git history to roll back in
+func requireGitMirror(mirrorDir string) error {
+	if _, err := os.Stat(filepath.Join(mirrorDir, ".git")); err != nil {
+		return fmt.Errorf("mirror %s has no git history (enable git.auto_commit)", mirrorDir)
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its standard output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %w\nOutput: %s", args[0], err, string(exitErr.Stderr))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
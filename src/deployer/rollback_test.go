@@ -0,0 +1,103 @@
+package deployer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"norsetinge/src/config"
+)
+
+// newTestMirror creates a git mirror with a deploy commit per site version
+func newTestMirror(t *testing.T, versions ...string) string {
+	t.Helper()
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "Norsetinge")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "deploy@norsetinge.com")
+	}
+
+	mirror := t.TempDir()
+	if _, err := git(mirror, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	for i, version := range versions {
+		writeSite(t, mirror, map[string]string{"index.html": version})
+		if i == 0 {
+			writeSite(t, mirror, map[string]string{"first-only.html": "old page"})
+		} else {
+			os.Remove(filepath.Join(mirror, "first-only.html"))
+		}
+		git(mirror, "add", "-A")
+		if _, err := git(mirror, "commit", "-q", "-m", deployCommitPrefix+version); err != nil {
+			t.Fatalf("git commit failed: %v", err)
+		}
+	}
+	return mirror
+}
+
+func TestRollback(t *testing.T) {
+	mirror := newTestMirror(t, "v1", "v2", "v3")
+	webhost := t.TempDir()
+	d := NewDeployer(&config.Config{Deploy: config.DeployConfig{Method: "local", Local: config.LocalConfig{Path: webhost}}})
+
+	deploys, err := d.ListDeploys(mirror, 2)
+	if err != nil {
+		t.Fatalf("ListDeploys failed: %v", err)
+	}
+	if len(deploys) != 2 || deploys[0].Subject != "Deploy: v3" || deploys[1].Subject != "Deploy: v2" {
+		t.Fatalf("Expected the two newest deploys, got %+v", deploys)
+	}
+
+	all, _ := d.ListDeploys(mirror, 10)
+	restored, err := d.Rollback(mirror, all[2].ShortHash())
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if restored.Subject != "Deploy: v1" {
+		t.Errorf("Restored the wrong commit: %+v", restored)
+	}
+
+	// Mirror and webhost hold v1 again, including the page v2 removed
+	for _, dir := range []string{mirror, webhost} {
+		if data, _ := os.ReadFile(filepath.Join(dir, "index.html")); string(data) != "v1" {
+			t.Errorf("%s: expected v1, got %q", dir, data)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "first-only.html")); err != nil {
+			t.Errorf("%s: page from v1 not restored", dir)
+		}
+	}
+
+	// The rollback is recorded on top; newer history is kept
+	history, _ := git(mirror, "log", "--format=%s")
+	subjects := strings.Split(strings.TrimSpace(history), "\n")
+	if len(subjects) != 4 || !strings.HasPrefix(subjects[0], rollbackCommitPrefix) || subjects[1] != "Deploy: v3" {
+		t.Errorf("Unexpected history after rollback: %q", subjects)
+	}
+	if status, _ := git(mirror, "status", "--porcelain"); status != "" {
+		t.Errorf("Mirror not clean after rollback:\n%s", status)
+	}
+	if deploys, _ := d.ListDeploys(mirror, 10); len(deploys) != 3 {
+		t.Errorf("Rollback commit should not be listed as a deploy, got %d", len(deploys))
+	}
+}
+
+func TestRollbackRejectsUnknownCommits(t *testing.T) {
+	mirror := newTestMirror(t, "v1")
+	git(mirror, "commit", "-q", "--allow-empty", "-m", "Manual fix")
+	head, _ := git(mirror, "rev-parse", "HEAD")
+	d := NewDeployer(&config.Config{})
+
+	for _, commit := range []string{"--output=/tmp/x", "deadbeef", strings.TrimSpace(head)} {
+		if _, err := d.Rollback(mirror, commit); !errors.Is(err, ErrUnknownCommit) {
+			t.Errorf("Rollback(%q): expected ErrUnknownCommit, got %v", commit, err)
+		}
+	}
+
+	if _, err := d.Rollback(t.TempDir(), "deadbeef"); err == nil || errors.Is(err, ErrUnknownCommit) {
+		t.Errorf("Expected a missing git history error, got %v", err)
+	}
+}
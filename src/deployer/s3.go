@@ -0,0 +1,313 @@
+package deployer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"norsetinge/src/config"
+)
+
+// s3Target deploys to an S3-compatible bucket using path-style requests signed with SigV4
+type s3Target struct {
+	cfg    config.S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3Target(cfg config.S3Config) (*s3Target, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 deploy needs deploy.s3.endpoint and bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 deploy needs an access key and secret key (S3_ACCESS_KEY, S3_SECRET_KEY)")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	return &s3Target{cfg: cfg, client: &http.Client{Timeout: 5 * time.Minute}, now: time.Now}, nil
+}
+
+func (t *s3Target) Name() string {
+	return "s3 " + path.Join(t.cfg.Bucket, t.cfg.Prefix)
+}
+
+// Deploy uploads files whose MD5 differs from the object's ETag and deletes objects
+// that are no longer in the source
+func (t *s3Target) Deploy(sourceDir string) error {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	changes, err := t.changes(sourceDir, files)
+	if err != nil {
+		return err
+	}
+
+	updated := changes.updated()
+	for _, file := range files {
+		if !updated[file.Path] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(sourceDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return err
+		}
+		if err := t.put(t.key(file.Path), data); err != nil {
+			return err
+		}
+	}
+
+	for _, sitePath := range changes.Deleted {
+		if err := t.do(http.MethodDelete, t.key(sitePath), nil, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changes compares the source files with the bucket by MD5 and ETag
+func (t *s3Target) changes(sourceDir string, files []sourceFile) (ChangeSet, error) {
+	changes := emptyChanges()
+
+	remote, err := t.list()
+	if err != nil {
+		return changes, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, file := range files {
+		wanted[file.Path] = true
+
+		data, err := os.ReadFile(filepath.Join(sourceDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return changes, err
+		}
+		sum := md5.Sum(data)
+		etag, exists := remote[t.key(file.Path)]
+		if !exists {
+			changes.Added = append(changes.Added, file.Path)
+		} else if strings.Trim(etag, `"`) != hex.EncodeToString(sum[:]) {
+			changes.Changed = append(changes.Changed, file.Path)
+		}
+	}
+
+	// Whatever is not wanted exists only in the bucket
+	var existing []string
+	for key := range remote {
+		existing = append(existing, t.sitePath(key))
+	}
+	changes.Deleted = staleFiles(existing, wanted)
+	return changes, nil
+}
+
+// key returns the object key for a site path
+func (t *s3Target) key(sitePath string) string {
+	if t.cfg.Prefix == "" {
+		return sitePath
+	}
+	return t.cfg.Prefix + "/" + sitePath
+}
+
+// sitePath returns the site path for an object key under the prefix
+func (t *s3Target) sitePath(key string) string {
+	if t.cfg.Prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, t.cfg.Prefix+"/")
+}
+
+// listBucketResult is the part of a ListObjectsV2 response we use
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+}
+
+// list returns the ETag of every object under the prefix, by key
+func (t *s3Target) list() (map[string]string, error) {
+	objects := make(map[string]string)
+	query := url.Values{"list-type": {"2"}}
+	if t.cfg.Prefix != "" {
+		query.Set("prefix", t.cfg.Prefix+"/")
+	}
+
+	for {
+		var page listBucketResult
+		err := t.do(http.MethodGet, "", query, nil, nil, func(body io.Reader) error {
+			return xml.NewDecoder(body).Decode(&page)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			objects[object.Key] = object.ETag
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// put uploads one object with a content type from its extension
+func (t *s3Target) put(key string, data []byte) error {
+	header := http.Header{}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	return t.do(http.MethodPut, key, nil, data, header, nil)
+}
+
+// do sends a signed request for key (the bucket itself if empty) and passes a
+// successful response body to read
+func (t *s3Target) do(method, key string, query url.Values, data []byte, header http.Header, read func(io.Reader) error) error {
+	u, err := url.Parse(t.cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	u.Path += "/" + t.cfg.Bucket + "/" + key
+	u.RawPath = s3Escape(u.Path, true)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	signV4(req, data, t.cfg.AccessKey, t.cfg.SecretKey, t.cfg.Region, t.now())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 %s %s failed: %w", method, key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("s3 %s %s failed: %s: %s", method, key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if read != nil {
+		return read(resp.Body)
+	}
+	return nil
+}
+
+// signV4 adds AWS Signature Version 4 headers for the s3 service.
+// Host and every header already on the request are signed.
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted and escaped as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name, false)+"="+s3Escape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters (and "/" if keepSlash)
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
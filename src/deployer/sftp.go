@@ -0,0 +1,295 @@
+package deployer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"norsetinge/src/config"
+)
+
+// sftpTarget deploys over SFTP in pure Go, for webhosts without rsync
+type sftpTarget struct {
+	cfg config.SFTPConfig
+}
+
+func newSFTPTarget(cfg config.SFTPConfig) (*sftpTarget, error) {
+	if cfg.Host == "" || cfg.User == "" || cfg.TargetPath == "" {
+		return nil, fmt.Errorf("sftp deploy needs deploy.sftp.host, user and target_path")
+	}
+	if cfg.SSHKey == "" && cfg.Password == "" {
+		return nil, fmt.Errorf("sftp deploy needs deploy.sftp.ssh_key or a password (SFTP_PASSWORD)")
+	}
+	return &sftpTarget{cfg: cfg}, nil
+}
+
+func (t *sftpTarget) Name() string {
+	return fmt.Sprintf("sftp %s@%s:%s", t.cfg.User, t.cfg.Host, t.cfg.TargetPath)
+}
+
+func (t *sftpTarget) Deploy(sourceDir string) error {
+	client, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return syncSFTP(client, sourceDir, t.cfg.TargetPath)
+}
+
+func (t *sftpTarget) DeployRelease(sourceDir, release string, keep int) error {
+	client, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return releaseSFTP(client, sourceDir, t.cfg.TargetPath, release, keep)
+}
+
+// connect opens an SFTP session; closing the client also closes the SSH connection
+func (t *sftpTarget) connect() (*sftp.Client, error) {
+	sshConfig, err := t.sshConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := t.cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	conn, err := ssh.Dial("tcp", addr, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connection failed: %w", err)
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sftp session failed: %w", err)
+	}
+	return client, nil
+}
+
+// sshConfig authenticates with the key (or password) and checks the host key against known_hosts
+func (t *sftpTarget) sshConfig() (*ssh.ClientConfig, error) {
+	knownHostsPath := t.cfg.KnownHosts
+	if knownHostsPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known_hosts: %w", err)
+		}
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if t.cfg.SSHKey != "" {
+		key, err := os.ReadFile(t.cfg.SSHKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if t.cfg.Password != "" {
+		auth = append(auth, ssh.Password(t.cfg.Password))
+	}
+
+	return &ssh.ClientConfig{User: t.cfg.User, Auth: auth, HostKeyCallback: hostKeyCallback}, nil
+}
+
+// syncSFTP uploads new and changed files (by size and mtime) to targetPath and deletes
+// remote files that are no longer in the source
+func syncSFTP(client *sftp.Client, sourceDir, targetPath string) error {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	changes, remoteDirs, err := sftpChanges(client, files, targetPath)
+	if err != nil {
+		return err
+	}
+
+	updated := changes.updated()
+	for _, file := range files {
+		if !updated[file.Path] {
+			continue
+		}
+		if err := uploadSFTP(client, filepath.Join(sourceDir, filepath.FromSlash(file.Path)), path.Join(targetPath, file.Path), file); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file.Path, err)
+		}
+	}
+
+	for _, rel := range changes.Deleted {
+		if err := client.Remove(path.Join(targetPath, rel)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+	}
+
+	// Deepest first, so parents are empty when we get to them
+	for i := len(remoteDirs) - 1; i >= 0; i-- {
+		client.RemoveDirectory(remoteDirs[i]) // Fails, harmlessly, unless empty
+	}
+	return nil
+}
+
+// sftpChanges compares the source files with targetPath by size and mtime (SFTP has
+// whole seconds). It also returns the remote directories, parents first.
+func sftpChanges(client *sftp.Client, files []sourceFile, targetPath string) (ChangeSet, []string, error) {
+	changes := emptyChanges()
+
+	remote := make(map[string]os.FileInfo)
+	var remoteDirs []string
+	walker := client.Walk(targetPath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			if os.IsNotExist(err) {
+				continue // First deploy
+			}
+			return changes, nil, fmt.Errorf("failed to list %s: %w", walker.Path(), err)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), targetPath), "/")
+		if walker.Stat().IsDir() {
+			if walker.Stat().Name() == ".git" {
+				walker.SkipDir()
+			} else if rel != "" {
+				remoteDirs = append(remoteDirs, walker.Path())
+			}
+			continue
+		}
+		remote[rel] = walker.Stat()
+	}
+
+	wanted := make(map[string]bool)
+	for _, file := range files {
+		wanted[file.Path] = true
+		existing, exists := remote[file.Path]
+		if !exists {
+			changes.Added = append(changes.Added, file.Path)
+		} else if existing.Size() != file.Size || existing.ModTime().Unix() != file.ModTime.Unix() {
+			changes.Changed = append(changes.Changed, file.Path)
+		}
+	}
+
+	// Whatever is not wanted exists only on the remote
+	var existing []string
+	for rel := range remote {
+		existing = append(existing, rel)
+	}
+	changes.Deleted = staleFiles(existing, wanted)
+	return changes, remoteDirs, nil
+}
+
+// uploadSFTP writes one file and sets its mtime, so the next deploy can skip it
+func uploadSFTP(client *sftp.Client, src, dest string, file sourceFile) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := client.MkdirAll(path.Dir(dest)); err != nil {
+		return err
+	}
+
+	out, err := client.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return client.Chtimes(dest, file.ModTime, file.ModTime)
+}
+
+// releaseSFTP uploads the site into releases/<release>/, hardlinking files unchanged
+// since the current release, then swaps current with posix-rename and prunes old releases
+func releaseSFTP(client *sftp.Client, sourceDir, targetPath, release string, keep int) error {
+	releasesDir := path.Join(targetPath, "releases")
+	releaseDir := path.Join(releasesDir, release)
+	currentLink := path.Join(targetPath, "current")
+
+	previous := ""
+	if info, err := client.Lstat(currentLink); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s exists and is not a symlink - move it away before enabling deploy.releases", currentLink)
+		}
+		target, err := client.ReadLink(currentLink)
+		if err != nil {
+			return fmt.Errorf("failed to read current symlink: %w", err)
+		}
+		previous = path.Base(target)
+	}
+
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return err
+	}
+	if err := client.MkdirAll(releaseDir); err != nil {
+		return fmt.Errorf("failed to create release directory: %w", err)
+	}
+
+	for _, file := range files {
+		dest := path.Join(releaseDir, file.Path)
+		if previous != "" {
+			old := path.Join(releasesDir, previous, file.Path)
+			if info, err := client.Stat(old); err == nil && info.Size() == file.Size && info.ModTime().Unix() == file.ModTime.Unix() {
+				if client.MkdirAll(path.Dir(dest)) == nil && client.Link(old, dest) == nil {
+					continue
+				}
+			}
+		}
+		if err := uploadSFTP(client, filepath.Join(sourceDir, filepath.FromSlash(file.Path)), dest, file); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", file.Path, err)
+		}
+	}
+
+	tmpLink := currentLink + ".tmp"
+	client.Remove(tmpLink)
+	if err := client.Symlink(path.Join("releases", release), tmpLink); err != nil {
+		return fmt.Errorf("failed to create current symlink: %w", err)
+	}
+	if err := client.PosixRename(tmpLink, currentLink); err != nil {
+		client.Remove(tmpLink)
+		return fmt.Errorf("failed to swap current symlink: %w", err)
+	}
+
+	entries, err := client.ReadDir(releasesDir)
+	if err != nil {
+		return fmt.Errorf("failed to list releases: %w", err)
+	}
+	var releases []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			releases = append(releases, entry.Name())
+		}
+	}
+	for _, stale := range staleReleases(releases, release, keep) {
+		if err := client.RemoveAll(path.Join(releasesDir, stale)); err != nil {
+			return fmt.Errorf("failed to remove release %s: %w", stale, err)
+		}
+	}
+	return nil
+}
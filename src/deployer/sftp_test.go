@@ -0,0 +1,77 @@
+package deployer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// pipeConn joins the read end of one pipe with the write end of another
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// newSFTPPair connects an SFTP client to an in-process SFTP server on the local filesystem
+func newSFTPPair(t *testing.T) *sftp.Client {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+
+	server, err := sftp.NewServer(pipeConn{serverRead, serverWrite})
+	if err != nil {
+		t.Fatalf("Failed to start sftp server: %v", err)
+	}
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatalf("Failed to start sftp client: %v", err)
+	}
+	t.Cleanup(func() {
+		server.Close() // Ends the client's read loop
+		client.Close()
+	})
+	return client
+}
+
+func TestSyncSFTP(t *testing.T) {
+	client := newSFTPPair(t)
+	source := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "www")
+
+	writeSite(t, source, map[string]string{
+		"index.html":              "home",
+		"artikel/da/a/index.html": "a",
+		"artikel/da/b/index.html": "b",
+		".git/HEAD":               "ref: refs/heads/main",
+	})
+	if err := syncSFTP(client, source, remote); err != nil {
+		t.Fatalf("First sync failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(remote, "artikel", "da", "b", "index.html")); string(data) != "b" {
+		t.Errorf("File not uploaded, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(remote, ".git")); !os.IsNotExist(err) {
+		t.Error("The mirror's .git directory was uploaded")
+	}
+
+	os.RemoveAll(filepath.Join(source, "artikel", "da", "b"))
+	writeSite(t, source, map[string]string{"index.html": "home, updated"})
+
+	if err := syncSFTP(client, source, remote); err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(remote, "index.html")); string(data) != "home, updated" {
+		t.Errorf("Changed file not uploaded, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "artikel", "da", "b")); !os.IsNotExist(err) {
+		t.Error("Removed article still on the remote")
+	}
+	if _, err := os.Stat(filepath.Join(remote, "artikel", "da", "a", "index.html")); err != nil {
+		t.Error("Unchanged article was removed")
+	}
+}
@@ -0,0 +1,272 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"norsetinge/src/config"
+)
+
+// DeployTarget is a place the built site is published to
+type DeployTarget interface {
+	// Name identifies the target in logs and errors
+	Name() string
+
+	// Deploy makes the target hold exactly the files in sourceDir
+	Deploy(sourceDir string) error
+}
+
+// newTargets creates the targets selected by deploy.method, in the configured order
+func newTargets(cfg *config.Config) ([]DeployTarget, error) {
+	var targets []DeployTarget
+	for _, method := range cfg.DeployMethods() {
+		var target DeployTarget
+		var err error
+
+		switch method {
+		case "rsync":
+			target, err = newRsyncTarget(cfg)
+		case "sftp":
+			target, err = newSFTPTarget(cfg.Deploy.SFTP)
+		case "local":
+			target, err = newLocalTarget(cfg.Deploy.Local)
+		case "s3":
+			target, err = newS3Target(cfg.Deploy.S3)
+		default:
+			err = fmt.Errorf("unknown deploy method %q", method)
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// deployTargets deploys sourceDir to every target. A failing target does not stop the
+// others; all failures are returned together. With deploy.releases every target gets
+// the same release name.
+func deployTargets(targets []DeployTarget, sourceDir string, releases config.ReleasesConfig) error {
+	release := ""
+	keep := releases.Keep
+	if releases.Enabled {
+		release = time.Now().UTC().Format(releaseFormat)
+		if keep <= 0 {
+			keep = defaultKeepReleases
+		}
+	}
+
+	var errs []error
+	for _, target := range targets {
+		var err error
+		if release == "" {
+			log.Printf("🌐 Deploying to %s...", target.Name())
+			err = target.Deploy(sourceDir)
+		} else if releaser, ok := target.(releaseTarget); ok {
+			log.Printf("🌐 Deploying release %s to %s...", release, target.Name())
+			err = releaser.DeployRelease(sourceDir, release, keep)
+		} else {
+			err = fmt.Errorf("release mode (deploy.releases) is not supported")
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.Name(), err))
+			continue
+		}
+		log.Printf("✓ Deployed to: %s", target.Name())
+	}
+	return errors.Join(errs...)
+}
+
+// sourceFile is a file to deploy, by slash-separated path relative to the source directory
+type sourceFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// listSource returns the files in dir, skipping the mirror's .git directory
+func listSource(dir string) ([]sourceFile, error) {
+	var files []sourceFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, sourceFile{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// rsyncTarget deploys with the rsync binary over SSH
+type rsyncTarget struct {
+	target string
+	opts   []string
+	sshKey string
+}
+
+// newRsyncTarget uses deploy.rsync_target, or the rsync section if that is not set
+func newRsyncTarget(cfg *config.Config) (*rsyncTarget, error) {
+	target := cfg.Deploy.RsyncTarget
+	if target == "" && cfg.Rsync.Host != "" {
+		target = fmt.Sprintf("%s@%s:%s", cfg.Rsync.User, cfg.Rsync.Host, cfg.Rsync.TargetPath)
+	}
+	if target == "" {
+		return nil, fmt.Errorf("rsync deploy needs deploy.rsync_target or rsync.host")
+	}
+
+	opts := strings.Fields(cfg.Deploy.RsyncOpts)
+	if len(opts) == 0 {
+		opts = []string{"-avz", "--delete"} // Remove files on remote that don't exist in source
+	}
+
+	return &rsyncTarget{target: target, opts: opts, sshKey: cfg.Rsync.SSHKey}, nil
+}
+
+func (t *rsyncTarget) Name() string {
+	return "rsync " + t.target
+}
+
+func (t *rsyncTarget) Deploy(sourceDir string) error {
+	args := append([]string{}, t.opts...)
+	args = append(args, "--exclude", ".git")
+
+	// Add SSH key if specified
+	if t.sshKey != "" {
+		args = append(args, "-e", fmt.Sprintf("ssh -i %s", t.sshKey))
+	}
+
+	// Add source and target
+	args = append(args, sourceDir+"/", t.target)
+
+	cmd := exec.Command("rsync", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync failed: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// localTarget copies the site into a directory on this machine
+type localTarget struct {
+	path string
+}
+
+func newLocalTarget(cfg config.LocalConfig) (*localTarget, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("local deploy needs deploy.local.path")
+	}
+	return &localTarget{path: cfg.Path}, nil
+}
+
+func (t *localTarget) Name() string {
+	return "local " + t.path
+}
+
+// Deploy copies new and changed files (by size and mtime, like rsync) and deletes
+// files that are no longer in the source
+func (t *localTarget) Deploy(sourceDir string) error {
+	files, err := listSource(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	changes, err := localChanges(files, t.path)
+	if err != nil {
+		return err
+	}
+
+	updated := changes.updated()
+	for _, file := range files {
+		if !updated[file.Path] {
+			continue
+		}
+		dest := filepath.Join(t.path, filepath.FromSlash(file.Path))
+		if err := copyFile(filepath.Join(sourceDir, filepath.FromSlash(file.Path)), dest, file.ModTime); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", file.Path, err)
+		}
+	}
+
+	for _, stale := range changes.Deleted {
+		if err := os.Remove(filepath.Join(t.path, filepath.FromSlash(stale))); err != nil {
+			return fmt.Errorf("failed to remove stale files: %w", err)
+		}
+	}
+
+	removeEmptyDirs(t.path)
+	return nil
+}
+
+// copyFile copies src to dest through a temporary file, so readers never see a partial file
+func copyFile(src, dest string, modTime time.Time) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Chtimes(tmp, modTime, modTime); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// removeEmptyDirs removes the empty subdirectories of dir
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+		sub := filepath.Join(dir, entry.Name())
+		removeEmptyDirs(sub)
+		os.Remove(sub) // Fails, harmlessly, unless empty
+	}
+}
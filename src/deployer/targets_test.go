@@ -0,0 +1,123 @@
+package deployer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"norsetinge/src/config"
+)
+
+// writeSite writes files (path → content) below dir
+func writeSite(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestNewTargets(t *testing.T) {
+	cfg := &config.Config{
+		Rsync: config.RsyncConfig{Host: "norsetinge.com", User: "deploy", TargetPath: "/var/www"},
+		Deploy: config.DeployConfig{
+			Method: "rsync, local, s3",
+			Local:  config.LocalConfig{Path: "/srv/site"},
+			S3:     config.S3Config{Endpoint: "http://minio:9000", Bucket: "site", AccessKey: "key", SecretKey: "secret"},
+		},
+	}
+
+	targets, err := newTargets(cfg)
+	if err != nil {
+		t.Fatalf("newTargets failed: %v", err)
+	}
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.Name())
+	}
+	if got := strings.Join(names, " | "); got != "rsync deploy@norsetinge.com:/var/www | local /srv/site | s3 site" {
+		t.Errorf("Unexpected targets: %s", got)
+	}
+
+	// Legacy config: no deploy.method, rsync.enabled decides
+	cfg.Deploy.Method = ""
+	if targets, _ := newTargets(cfg); len(targets) != 0 {
+		t.Errorf("Expected no targets with rsync disabled, got %d", len(targets))
+	}
+
+	cfg.Deploy.Method = "sftp"
+	if _, err := newTargets(cfg); err == nil {
+		t.Error("Expected sftp without host to fail")
+	}
+}
+
+// failingTarget is a target that always fails
+type failingTarget struct{}
+
+func (failingTarget) Name() string                  { return "broken" }
+func (failingTarget) Deploy(sourceDir string) error { return errors.New("unreachable") }
+
+func TestDeployTargetsRunsAll(t *testing.T) {
+	source := t.TempDir()
+	writeSite(t, source, map[string]string{"index.html": "home"})
+	dest := t.TempDir()
+
+	err := deployTargets([]DeployTarget{failingTarget{}, &localTarget{path: dest}}, source, config.ReleasesConfig{})
+	if err == nil || !strings.Contains(err.Error(), "broken: unreachable") {
+		t.Errorf("Expected the failing target's error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "index.html")); err != nil {
+		t.Error("Target after a failing one was not deployed")
+	}
+}
+
+func TestLocalTarget(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	target := &localTarget{path: dest}
+
+	writeSite(t, source, map[string]string{
+		"index.html":              "home",
+		"artikel/da/a/index.html": "a",
+		"artikel/da/b/index.html": "b",
+		".git/HEAD":               "ref: refs/heads/main",
+	})
+	if err := target.Deploy(source); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ".git")); !os.IsNotExist(err) {
+		t.Error("The mirror's .git directory was deployed")
+	}
+
+	// Same size and mtime as the source: must be left alone
+	unchanged := filepath.Join(dest, "artikel", "da", "a", "index.html")
+	info, _ := os.Stat(filepath.Join(source, "artikel", "da", "a", "index.html"))
+	os.WriteFile(unchanged, []byte("u"), 0644)
+	os.Chtimes(unchanged, info.ModTime(), info.ModTime())
+
+	// Change home, remove b, keep a
+	os.RemoveAll(filepath.Join(source, "artikel", "da", "b"))
+	writeSite(t, source, map[string]string{"index.html": "home v2"})
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(source, "index.html"), future, future)
+
+	if err := target.Deploy(source); err != nil {
+		t.Fatalf("Second deploy failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dest, "index.html")); string(data) != "home v2" {
+		t.Errorf("Changed file not copied, got %q", data)
+	}
+	if data, _ := os.ReadFile(unchanged); string(data) != "u" {
+		t.Errorf("File with unchanged size and mtime was rewritten, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "artikel", "da", "b")); !os.IsNotExist(err) {
+		t.Error("Removed article still deployed")
+	}
+}
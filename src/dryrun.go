@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"norsetinge/src/builder"
+	"norsetinge/src/config"
+	"norsetinge/src/deployer"
+)
+
+// runDryRun prints what deploying the last build (hugo.public_dir) would add, change
+// and delete in the mirror and on every deploy target. Nothing is written.
+func runDryRun(cfg *config.Config) error {
+	// Wait for a build or deploy running in the approval server
+	lock := builder.NewDeployLock(cfg)
+	if err := lock.Lock(); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	report, err := deployer.NewDeployer(cfg).Plan(cfg.Hugo.PublicDir, cfg.Hugo.MirrorDir)
+	if err != nil {
+		return err
+	}
+
+	printChanges("mirror "+cfg.Hugo.MirrorDir, report.Mirror)
+	for _, target := range report.Targets {
+		if target.Error != "" {
+			fmt.Printf("%s: failed: %s\n", target.Name, target.Error)
+			continue
+		}
+		printChanges(target.Name, target.ChangeSet)
+	}
+
+	if limit := cfg.Deploy.MaxDeletes; limit > 0 && report.Deletes() > limit {
+		fmt.Printf("\nThe next deploy will stop: %d deletes is above deploy.max_deletes (%d).\n", report.Deletes(), limit)
+		fmt.Printf("Confirm with: POST /api/v1/deploy {\"confirm_deletes\": %d}\n", report.Deletes())
+	}
+	return nil
+}
+
+// printChanges prints one destination's change set, one file per line
+func printChanges(name string, changes deployer.ChangeSet) {
+	fmt.Printf("%s: %s\n", name, changes)
+	for _, p := range changes.Added {
+		fmt.Printf("  + %s\n", p)
+	}
+	for _, p := range changes.Changed {
+		fmt.Printf("  ~ %s\n", p)
+	}
+	for _, p := range changes.Deleted {
+		fmt.Printf("  - %s\n", p)
+	}
+}
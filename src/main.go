@@ -7,12 +7,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"norsetinge/src/approval"
-	"norsetinge/src/builder"
 	"norsetinge/src/config"
-	"norsetinge/src/deployer"
 	"norsetinge/src/watcher"
 )
 
@@ -31,6 +28,22 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Subcommands: "rollback" lists recent deploys, "rollback <commit>" redeploys one
+	if flag.Arg(0) == "rollback" {
+		if err := runRollback(cfg, flag.Arg(1)); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		return
+	}
+
+	// "dry-run" lists what deploying the last build would change, without writing
+	if flag.Arg(0) == "dry-run" {
+		if err := runDryRun(cfg); err != nil {
+			log.Fatalf("Dry-run failed: %v", err)
+		}
+		return
+	}
+
 	log.Printf("Loaded config: monitoring %s", cfg.Dropbox.BasePath)
 
 	// Create approval server
@@ -56,6 +69,9 @@ func main() {
 	// Connect mover to approval server so it can move files
 	approvalServer.SetMover(w.GetMover())
 
+	// Continue articles that were mid-flow at the last shutdown
+	approvalServer.Resume()
+
 	// Start watching
 	if err := w.Start(); err != nil {
 		log.Fatalf("Failed to start watcher: %v", err)
@@ -63,35 +79,16 @@ func main() {
 
 	log.Println("Watcher started. Monitoring for article changes...")
 
-	// Email notifications disabled - using ntfy only
-
-	// Start periodic build+deploy (every 10 minutes)
-	go func() {
-		ticker := time.NewTicker(10 * time.Minute)
-		defer ticker.Stop()
-
-		hugoBuilder := builder.NewHugoBuilder(cfg)
-		dep := deployer.NewDeployer(cfg)
-
-		for range ticker.C {
-			log.Printf("⏰ Running periodic build+deploy...")
-
-			// Build full site
-			publicDir, mirrorDir, err := hugoBuilder.BuildFullSite()
-			if err != nil {
-				log.Printf("Error in periodic build: %v", err)
-				continue
-			}
-
-			// Deploy
-			if err := dep.Deploy(publicDir, mirrorDir); err != nil {
-				log.Printf("Error in periodic deploy: %v", err)
-				continue
-			}
+	// Poll the approval inbox for email replies (GODKEND / AFVIS / comments)
+	stop := make(chan struct{})
+	defer close(stop)
+	if poller := approval.NewIMAPPoller(cfg, approvalServer); poller.Enabled() {
+		go poller.Start(stop)
+	}
 
-			log.Printf("✅ Periodic build+deploy completed")
-		}
-	}()
+	// Build+deploy when published content changes, with a periodic fallback
+	scheduler := approvalServer.Scheduler()
+	go scheduler.Start(stop)
 
 	log.Println("Press Ctrl+C to stop")
 
@@ -99,6 +96,7 @@ func main() {
 	go func() {
 		for event := range w.Events() {
 			log.Printf("📄 Event: %v - %s", event.Type, event.FilePath)
+			scheduler.Notify(event.FilePath)
 		}
 	}()
 
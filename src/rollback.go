@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"norsetinge/src/builder"
+	"norsetinge/src/config"
+	"norsetinge/src/deployer"
+)
+
+// rollbackListLimit is the number of deploys listed by "norsetinge rollback"
+const rollbackListLimit = 20
+
+// runRollback lists the recent deploys in the mirror, or with a commit rolls the site back to it
+func runRollback(cfg *config.Config, commit string) error {
+	d := deployer.NewDeployer(cfg)
+
+	if commit == "" {
+		deploys, err := d.ListDeploys(cfg.Hugo.MirrorDir, rollbackListLimit)
+		if err != nil {
+			return err
+		}
+		if len(deploys) == 0 {
+			fmt.Println("No deploys in the mirror's git history")
+			return nil
+		}
+
+		fmt.Println("Recent deploys (newest first):")
+		for _, deploy := range deploys {
+			fmt.Printf("  %s  %s\n", deploy.ShortHash(), deploy.Subject)
+		}
+		fmt.Println("\nRoll back with: norsetinge rollback <commit>")
+		return nil
+	}
+
+	// Wait for a build or deploy running in the approval server
+	lock := builder.NewDeployLock(cfg)
+	if err := lock.Lock(); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	restored, err := d.Rollback(cfg.Hugo.MirrorDir, commit)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back to %s (%s)\n", restored.ShortHash(), restored.Subject)
+	return nil
+}
@@ -0,0 +1,266 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+// Translator translates approved articles via the OpenRouter API
+type Translator struct {
+	cfg    *config.Config
+	client *http.Client
+}
+
+// chatMessage is a single message in an OpenRouter chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the OpenRouter (OpenAI-compatible) chat completion request body
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// chatResponse is the subset of the chat completion response we need
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// translatedFields holds the parts of an article the model translates.
+// Everything else (ID, author, tags, images, ...) is copied from the source.
+type translatedFields struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+// NewTranslator creates a new OpenRouter translator
+func NewTranslator(cfg *config.Config) *Translator {
+	return &Translator{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Enabled reports whether an API key and target languages are configured
+func (t *Translator) Enabled() bool {
+	return t.cfg.OpenRouter.APIKey != "" && len(t.cfg.Languages) > 0
+}
+
+// SourceLanguage returns the article's language, defaulting to Danish
+func SourceLanguage(article *common.Article) string {
+	if article.Language != "" {
+		return article.Language
+	}
+	return "da"
+}
+
+// TranslateAll translates the article to every configured language except its own
+// and writes one translated article file per language.
+// The source article is not modified. Failed languages are reported together.
+func (t *Translator) TranslateAll(article *common.Article) ([]*common.Article, error) {
+	source := SourceLanguage(article)
+
+	var translations []*common.Article
+	var errs []error
+
+	for _, lang := range t.cfg.Languages {
+		if lang == source {
+			continue
+		}
+
+		translated, err := t.Translate(article, lang)
+		if err != nil {
+			log.Printf("Warning: Failed to translate %s to %s: %v", article.ID, lang, err)
+			errs = append(errs, fmt.Errorf("%s: %w", lang, err))
+			continue
+		}
+
+		if err := translated.WriteFrontmatter(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to write translation: %w", lang, err))
+			continue
+		}
+
+		log.Printf("  🌍 Translated to %s: %s", lang, translated.Title)
+		translations = append(translations, translated)
+	}
+
+	return translations, errors.Join(errs...)
+}
+
+// Translate returns a copy of the article translated to lang.
+// The copy's FilePath points at its location in the translations folder.
+func (t *Translator) Translate(article *common.Article, lang string) (*common.Article, error) {
+	fields, err := t.requestTranslation(article, SourceLanguage(article), lang)
+	if err != nil {
+		return nil, err
+	}
+
+	translated := *article
+	translated.Language = lang
+	translated.Title = fields.Title
+	translated.Content = fields.Content
+	if fields.Description != "" {
+		translated.Description = fields.Description
+	}
+	translated.FilePath = t.TranslationPath(article, lang)
+
+	if err := os.MkdirAll(filepath.Dir(translated.FilePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create translations directory: %w", err)
+	}
+
+	return &translated, nil
+}
+
+// TranslationsDir returns the folder holding all translations
+func (t *Translator) TranslationsDir() string {
+	folder := "translations"
+	if name, ok := t.cfg.Aliases[t.cfg.Dropbox.FolderLanguage]["translations"]; ok {
+		folder = name
+	}
+	return filepath.Join(t.cfg.Dropbox.BasePath, folder)
+}
+
+// TranslationPath returns where the translation of an article to lang is stored
+func (t *Translator) TranslationPath(article *common.Article, lang string) string {
+	return filepath.Join(t.TranslationsDir(), article.GetIDSlug(), lang+".md")
+}
+
+// LoadTranslations loads all stored translations of an article
+func (t *Translator) LoadTranslations(article *common.Article) ([]*common.Article, error) {
+	dir := filepath.Join(t.TranslationsDir(), article.GetIDSlug())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // Not translated yet
+		}
+		return nil, fmt.Errorf("failed to read translations directory: %w", err)
+	}
+
+	var translations []*common.Article
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		translation, err := common.ParseArticle(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("Warning: Failed to parse translation %s: %v", entry.Name(), err)
+			continue
+		}
+		translations = append(translations, translation)
+	}
+
+	return translations, nil
+}
+
+// requestTranslation calls the OpenRouter chat completions endpoint
+func (t *Translator) requestTranslation(article *common.Article, from, to string) (*translatedFields, error) {
+	source, err := json.Marshal(translatedFields{
+		Title:       article.Title,
+		Description: article.Description,
+		Content:     article.Content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal article: %w", err)
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model: t.cfg.OpenRouter.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt(from, to)},
+			{Role: "user", Content: string(source)},
+		},
+		ResponseFormat: &responseFormat{Type: "json_object"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := strings.TrimRight(t.cfg.OpenRouter.Endpoint, "/") + "/chat/completions"
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+t.cfg.OpenRouter.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Title", "Norsetinge")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openrouter request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var chat chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("failed to decode openrouter response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if chat.Error != nil {
+			return nil, fmt.Errorf("openrouter returned status %d: %s", resp.StatusCode, chat.Error.Message)
+		}
+		return nil, fmt.Errorf("openrouter returned status %d", resp.StatusCode)
+	}
+
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("openrouter returned no choices")
+	}
+
+	var fields translatedFields
+	if err := json.Unmarshal([]byte(stripCodeFence(chat.Choices[0].Message.Content)), &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse translated article: %w", err)
+	}
+
+	if fields.Title == "" || fields.Content == "" {
+		return nil, fmt.Errorf("translation is missing title or content")
+	}
+
+	return &fields, nil
+}
+
+// systemPrompt builds the instructions for translating from one language to another
+func systemPrompt(from, to string) string {
+	return fmt.Sprintf(`You are a professional news translator.
+Translate the article from language "%s" to language "%s" (ISO 639-1 codes).
+The input is a JSON object with the keys "title", "description" and "content".
+Keep Markdown formatting, links, image references, Hugo shortcodes ({{< ... >}}) and code blocks unchanged.
+Respond with a single JSON object with the same keys and the translated text as values.`, from, to)
+}
+
+// stripCodeFence removes a surrounding ```json ... ``` fence some models add
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,170 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"norsetinge/src/common"
+	"norsetinge/src/config"
+)
+
+// newFakeOpenRouter starts a local server answering chat completions
+// with a "translation" that prefixes the title with the target language
+func newFakeOpenRouter(t *testing.T) *httptest.Server {
+	targetLang := regexp.MustCompile(`to language "([a-z]+)"`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/chat/completions" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Missing or wrong Authorization header: %q", r.Header.Get("Authorization"))
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "test/model" {
+			t.Errorf("Expected model 'test/model', got '%s'", req.Model)
+		}
+
+		lang := targetLang.FindStringSubmatch(req.Messages[0].Content)[1]
+
+		var source translatedFields
+		json.Unmarshal([]byte(req.Messages[1].Content), &source)
+
+		translated, _ := json.Marshal(translatedFields{
+			Title:       "[" + lang + "] " + source.Title,
+			Description: "[" + lang + "] " + source.Description,
+			Content:     "[" + lang + "] " + source.Content,
+		})
+
+		// Wrap in a code fence like some models do
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": "```json\n" + string(translated) + "\n```"}},
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestTranslateAll(t *testing.T) {
+	server := newFakeOpenRouter(t)
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{
+			BasePath:       tmpDir,
+			FolderLanguage: "da",
+		},
+		OpenRouter: config.OpenRouterConfig{
+			APIKey:   "test-key",
+			Model:    "test/model",
+			Endpoint: server.URL + "/api/v1",
+		},
+		Languages: []string{"en", "da", "sv"},
+		Aliases: config.FolderAliases{
+			"da": {"translations": "oversaettelser"},
+		},
+	}
+
+	article := &common.Article{
+		ID:          "#ABC123",
+		Title:       "Nyhed",
+		Author:      "TB",
+		Description: "Kort beskrivelse",
+		Tags:        []string{"devops", "nyheder"},
+		Images:      []string{"billede.jpg"},
+		Content:     "Indhold med [link](https://example.com).",
+	}
+
+	translator := NewTranslator(cfg)
+	if !translator.Enabled() {
+		t.Fatal("Translator should be enabled with API key and languages")
+	}
+
+	translations, err := translator.TranslateAll(article)
+	if err != nil {
+		t.Fatalf("TranslateAll failed: %v", err)
+	}
+
+	// Source language (da, the default) must be skipped
+	if len(translations) != 2 {
+		t.Fatalf("Expected 2 translations, got %d", len(translations))
+	}
+
+	if article.Language != "" {
+		t.Errorf("Source article language should be unchanged, got '%s'", article.Language)
+	}
+
+	for _, lang := range []string{"en", "sv"} {
+		path := filepath.Join(tmpDir, "oversaettelser", "abc123", lang+".md")
+		parsed, err := common.ParseArticle(path)
+		if err != nil {
+			t.Fatalf("Failed to parse %s translation: %v", lang, err)
+		}
+
+		if parsed.ID != article.ID {
+			t.Errorf("%s: expected ID %s, got %s", lang, article.ID, parsed.ID)
+		}
+		if parsed.Author != article.Author {
+			t.Errorf("%s: expected author %s, got %s", lang, article.Author, parsed.Author)
+		}
+		if parsed.Language != lang {
+			t.Errorf("%s: expected language %s, got %s", lang, lang, parsed.Language)
+		}
+		if parsed.Title != "["+lang+"] Nyhed" {
+			t.Errorf("%s: unexpected title '%s'", lang, parsed.Title)
+		}
+		if len(parsed.Tags) != 2 || parsed.Tags[0] != "devops" {
+			t.Errorf("%s: tags not preserved: %v", lang, parsed.Tags)
+		}
+		if len(parsed.Images) != 1 || parsed.Images[0] != "billede.jpg" {
+			t.Errorf("%s: images not preserved: %v", lang, parsed.Images)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "oversaettelser", "abc123", "da.md")); !os.IsNotExist(err) {
+		t.Error("No translation should be written for the source language")
+	}
+
+	loaded, err := translator.LoadTranslations(article)
+	if err != nil {
+		t.Fatalf("LoadTranslations failed: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Errorf("Expected 2 loaded translations, got %d", len(loaded))
+	}
+}
+
+func TestTranslateAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "invalid key"}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Dropbox:    config.DropboxConfig{BasePath: t.TempDir()},
+		OpenRouter: config.OpenRouterConfig{APIKey: "bad", Endpoint: server.URL},
+		Languages:  []string{"en"},
+	}
+
+	article := &common.Article{ID: "#ABC123", Title: "Nyhed", Author: "TB", Content: "Indhold"}
+
+	translations, err := NewTranslator(cfg).TranslateAll(article)
+	if err == nil {
+		t.Fatal("Expected error for unauthorized response")
+	}
+	if len(translations) != 0 {
+		t.Errorf("Expected no translations, got %d", len(translations))
+	}
+}
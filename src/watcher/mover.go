@@ -95,7 +95,7 @@ func (m *Mover) ProcessArticleStatusChange(filePath string) error {
 
 // GetAllMonitoredFolders returns all folders that should be monitored
 func (m *Mover) GetAllMonitoredFolders() ([]string, error) {
-	statuses := []string{"draft", "revision", "publish", "published", "rejected", "update"}
+	statuses := []string{"draft", "revision", "publish", "published", "rejected", "update", "unpublish"}
 	folders := make([]string, 0, len(statuses))
 
 	for _, status := range statuses {
@@ -5,17 +5,33 @@ import (
 	"path/filepath"
 	"testing"
 
+	"gopkg.in/yaml.v3"
+
 	"norsetinge/src/common"
 	"norsetinge/src/config"
 )
 
+// testAliases loads the project's folder-aliases.yaml
+func testAliases(t *testing.T) config.FolderAliases {
+	data, err := os.ReadFile("../../folder-aliases.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read folder aliases: %v", err)
+	}
+
+	var aliases config.FolderAliases
+	if err := yaml.Unmarshal(data, &aliases); err != nil {
+		t.Fatalf("Failed to parse folder aliases: %v", err)
+	}
+	return aliases
+}
+
 func TestMoveArticle(t *testing.T) {
 	// Create temp directory structure
 	tmpDir := t.TempDir()
 	basePath := filepath.Join(tmpDir, "NorseTinge")
 
 	// Create folders
-	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater"}
+	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater", "tilbagetrukket"}
 	for _, folder := range folders {
 		if err := os.MkdirAll(filepath.Join(basePath, folder), 0755); err != nil {
 			t.Fatalf("Failed to create folder: %v", err)
@@ -48,9 +64,10 @@ Test content
 			BasePath:       basePath,
 			FolderLanguage: "da",
 		},
+		Aliases: testAliases(t),
 	}
 
-	mover, err := NewMover(cfg, "")
+	mover, err := NewMover(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create mover: %v", err)
 	}
@@ -99,9 +116,10 @@ func TestGetFolderForStatus(t *testing.T) {
 			BasePath:       "/test/base",
 			FolderLanguage: "da",
 		},
+		Aliases: testAliases(t),
 	}
 
-	mover, err := NewMover(cfg, "")
+	mover, err := NewMover(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create mover: %v", err)
 	}
@@ -115,6 +133,7 @@ func TestGetFolderForStatus(t *testing.T) {
 		{"published", "/test/base/udgivet"},
 		{"rejected", "/test/base/afvist"},
 		{"revision", "/test/base/afventer-rettelser"},
+		{"unpublish", "/test/base/tilbagetrukket"},
 	}
 
 	for _, tt := range tests {
@@ -136,9 +155,10 @@ func TestGetAllMonitoredFolders(t *testing.T) {
 			BasePath:       "/test",
 			FolderLanguage: "da",
 		},
+		Aliases: testAliases(t),
 	}
 
-	mover, err := NewMover(cfg, "")
+	mover, err := NewMover(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create mover: %v", err)
 	}
@@ -148,7 +168,7 @@ func TestGetAllMonitoredFolders(t *testing.T) {
 		t.Fatalf("GetAllMonitoredFolders failed: %v", err)
 	}
 
-	if len(folders) != 6 {
-		t.Errorf("Expected 6 folders, got %d", len(folders))
+	if len(folders) != 7 {
+		t.Errorf("Expected 7 folders, got %d", len(folders))
 	}
 }
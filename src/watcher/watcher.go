@@ -21,9 +21,10 @@ type Watcher struct {
 	approvalServer ApprovalServer
 }
 
-// ApprovalServer interface for triggering approval
+// ApprovalServer interface for triggering approval and takedown
 type ApprovalServer interface {
 	RequestApproval(article *common.Article) error
+	Unpublish(article *common.Article) error
 }
 
 // Event represents a file system event
@@ -179,7 +180,11 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 
 		// Trigger approval for publish status OR update flag
 		currentStatus := article.GetCurrentStatus()
-		if (currentStatus == "publish" || currentStatus == "update") && w.approvalServer != nil {
+		if currentStatus == "unpublish" && w.approvalServer != nil {
+			if err := w.approvalServer.Unpublish(article); err != nil {
+				log.Printf("Failed to unpublish: %v", err)
+			}
+		} else if (currentStatus == "publish" || currentStatus == "update") && w.approvalServer != nil {
 			log.Printf("Triggering approval for: %s (status: %s)", article.Title, currentStatus)
 			if err := w.approvalServer.RequestApproval(article); err != nil {
 				log.Printf("Failed to request approval: %v", err)
@@ -286,23 +291,26 @@ func (w *Watcher) processArticleFile(filePath string) error {
 		return fmt.Errorf("failed to parse article: %w", err)
 	}
 
-	// Process status changes (will move file if needed)
-	if err := w.mover.ProcessArticleStatusChange(filePath); err != nil {
+	// Ignore articles with no status set
+	currentStatus := article.GetCurrentStatus()
+	if currentStatus == "unknown" {
+		return nil
+	}
+
+	// Move to the folder for its status (updates article.FilePath)
+	if err := w.mover.MoveArticle(article); err != nil {
 		return fmt.Errorf("failed to process status change: %w", err)
 	}
 
-	// Re-parse to get updated file path after potential move
-	article, err = common.ParseArticle(article.FilePath)
-	if err != nil {
-		// File may have been moved, try original path
-		article, err = common.ParseArticle(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to re-parse article: %w", err)
+	// Check if article needs approval (publish or update status)
+	if currentStatus == "unpublish" && w.approvalServer != nil {
+		// Takedown - the server ignores articles it has already unpublished
+		if err := w.approvalServer.Unpublish(article); err != nil {
+			return fmt.Errorf("failed to unpublish: %w", err)
 		}
+		return nil
 	}
 
-	// Check if article needs approval (publish or update status)
-	currentStatus := article.GetCurrentStatus()
 	if (currentStatus == "publish" || currentStatus == "update") && w.approvalServer != nil {
 		// Check if already pending approval
 		// We don't want to spam approval requests for articles already in the queue
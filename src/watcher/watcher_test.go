@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"norsetinge/src/common"
 	"norsetinge/src/config"
 )
 
@@ -15,7 +16,7 @@ func TestWatcher(t *testing.T) {
 	basePath := filepath.Join(tmpDir, "NorseTinge")
 
 	// Create all required folders
-	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater"}
+	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater", "tilbagetrukket"}
 	for _, folder := range folders {
 		if err := os.MkdirAll(filepath.Join(basePath, folder), 0755); err != nil {
 			t.Fatalf("Failed to create folder: %v", err)
@@ -28,10 +29,11 @@ func TestWatcher(t *testing.T) {
 			BasePath:       basePath,
 			FolderLanguage: "da",
 		},
+		Aliases: testAliases(t),
 	}
 
 	// Create watcher
-	w, err := NewWatcher(cfg, "")
+	w, err := NewWatcher(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create watcher: %v", err)
 	}
@@ -122,7 +124,7 @@ func TestWatcherIgnoresNonMarkdownFiles(t *testing.T) {
 	basePath := filepath.Join(tmpDir, "NorseTinge")
 
 	// Create all required folders
-	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater"}
+	folders := []string{"kladde", "udgiv", "udgivet", "afvist", "afventer-rettelser", "opdater", "tilbagetrukket"}
 	for _, folder := range folders {
 		if err := os.MkdirAll(filepath.Join(basePath, folder), 0755); err != nil {
 			t.Fatalf("Failed to create folder: %v", err)
@@ -134,9 +136,10 @@ func TestWatcherIgnoresNonMarkdownFiles(t *testing.T) {
 			BasePath:       basePath,
 			FolderLanguage: "da",
 		},
+		Aliases: testAliases(t),
 	}
 
-	w, err := NewWatcher(cfg, "")
+	w, err := NewWatcher(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create watcher: %v", err)
 	}
@@ -160,3 +163,60 @@ func TestWatcherIgnoresNonMarkdownFiles(t *testing.T) {
 		// Expected - no event received
 	}
 }
+
+// fakeApprovalServer records calls from the watcher
+type fakeApprovalServer struct {
+	requested   []string
+	unpublished []string
+}
+
+func (f *fakeApprovalServer) RequestApproval(article *common.Article) error {
+	f.requested = append(f.requested, article.ID)
+	return nil
+}
+
+func (f *fakeApprovalServer) Unpublish(article *common.Article) error {
+	f.unpublished = append(f.unpublished, article.ID)
+	return nil
+}
+
+func TestScanUnpublishesArticle(t *testing.T) {
+	tmpDir := t.TempDir()
+	basePath := filepath.Join(tmpDir, "NorseTinge")
+
+	cfg := &config.Config{
+		Dropbox: config.DropboxConfig{
+			BasePath:       basePath,
+			FolderLanguage: "da",
+		},
+		Aliases: testAliases(t),
+	}
+
+	w, err := NewWatcher(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	server := &fakeApprovalServer{}
+	w.SetApprovalServer(server)
+
+	publishedDir := filepath.Join(basePath, "udgivet")
+	os.MkdirAll(publishedDir, 0755)
+	testFile := filepath.Join(publishedDir, "test.md")
+	os.WriteFile(testFile, []byte("---\nid: \"#UNP001\"\ntitle: Take down\nauthor: TB\nstatus:\n  published: 0\n  unpublish: 1\n---\n\nBody\n"), 0644)
+
+	if err := w.processArticleFile(testFile); err != nil {
+		t.Fatalf("processArticleFile failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(basePath, "tilbagetrukket", "test.md")); err != nil {
+		t.Errorf("Article not moved to tilbagetrukket/: %v", err)
+	}
+	if len(server.unpublished) != 1 || server.unpublished[0] != "#UNP001" {
+		t.Errorf("Expected Unpublish(#UNP001), got %v", server.unpublished)
+	}
+	if len(server.requested) != 0 {
+		t.Errorf("Unpublished article sent for approval: %v", server.requested)
+	}
+}